@@ -0,0 +1,182 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package math
+
+import (
+    "fmt"
+    "math/big"
+    "github.com/nucypher/goUmbral/openssl"
+)
+
+// Returns the inverses of the ModBigNums, computed with Montgomery's trick:
+// a single modular inversion and 3(n - 1) multiplications.
+//
+// The ModBigNums must all use the same curve and none may be zero.
+// The inverses must be freed by the calling function.
+func BatchInvert(nums []*ModBigNum) ([]*ModBigNum, error) {
+    if len(nums) == 0 {
+        return nil, nil
+    }
+    curve := nums[0].Curve
+    for _, num := range nums {
+        if !num.Curve.Equals(curve) {
+            return nil, fmt.Errorf("ModBigNum BatchInvert Error: %w", ErrCurveMismatch)
+        }
+    }
+
+    // prefixes[i] = nums[0] * ... * nums[i]
+    prefixes := make([]*ModBigNum, len(nums))
+    defer freeModBNs(prefixes)
+
+    var err error
+    prefixes[0], err = nums[0].Copy()
+    if err != nil {
+        return nil, err
+    }
+
+    for i := 1; i < len(nums); i++ {
+        prefixes[i] = newModBigNum(openssl.NewBigNum(), curve)
+        err = prefixes[i].Mul(prefixes[i - 1], nums[i])
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    // inv = 1 / (nums[0] * ... * nums[i]), from i = n - 1 down to 0.
+    inv, err := prefixes[len(nums) - 1].Copy()
+    if err != nil {
+        return nil, err
+    }
+
+    err = inv.Invert(inv)
+    if err != nil {
+        inv.Free()
+        return nil, err
+    }
+
+    invs := make([]*ModBigNum, len(nums))
+    for i := len(nums) - 1; i > 0; i-- {
+        invs[i] = newModBigNum(openssl.NewBigNum(), curve)
+        err = invs[i].Mul(inv, prefixes[i - 1])
+        if err == nil {
+            err = inv.Mul(inv, nums[i])
+        }
+        if err != nil {
+            inv.Free()
+            freeModBNs(invs)
+            return nil, err
+        }
+    }
+    invs[0] = inv
+    return invs, nil
+}
+
+// Returns the affine coordinates of the Points, normalizing them all
+// with a single field inversion instead of one per Point.
+//
+// The Points must all use the same curve. They are not modified,
+// so they may be shared with other goroutines.
+func BatchToAffine(points []*Point) ([]*big.Int, []*big.Int, error) {
+    copies, err := affineCopies(points)
+    if err != nil {
+        return nil, nil, err
+    }
+    defer freePoints(copies)
+
+    xs := make([]*big.Int, len(points))
+    ys := make([]*big.Int, len(points))
+    for i, point := range copies {
+        xs[i], ys[i], err = point.ToAffine()
+        if err != nil {
+            return nil, nil, err
+        }
+    }
+    return xs, ys, nil
+}
+
+// Returns the serializations of the Points, as Point.ToBytes() does,
+// normalizing them all with a single field inversion.
+//
+// The Points must all use the same curve. They are not modified,
+// so they may be shared with other goroutines.
+func BatchToBytes(points []*Point, isCompressed bool) ([][]byte, error) {
+    xs, ys, err := BatchToAffine(points)
+    if err != nil {
+        return nil, err
+    }
+
+    data := make([][]byte, len(points))
+    for i, point := range points {
+        data[i] = affineToBytes(xs[i], ys[i], point.Curve, isCompressed)
+    }
+    return data, nil
+}
+
+// Returns copies of the Points in affine coordinates.
+//
+// EC_POINTs_make_affine rewrites the EC_POINTs it is given, so the Points
+// themselves are left alone: other goroutines may be reading them.
+// The copies must be freed by the calling function.
+func affineCopies(points []*Point) ([]*Point, error) {
+    if len(points) == 0 {
+        return nil, nil
+    }
+    curve := points[0].Curve
+
+    copies := make([]*Point, len(points))
+    ecPoints := make([]openssl.ECPoint, len(points))
+    for i, point := range points {
+        if !point.Curve.Equals(curve) {
+            freePoints(copies)
+            return nil, ErrCurveMismatch
+        }
+
+        var err error
+        copies[i], err = point.Copy()
+        if err != nil {
+            freePoints(copies)
+            return nil, err
+        }
+        ecPoints[i] = copies[i].ECPoint
+    }
+
+    ctx := openssl.GetBNCtx()
+    defer openssl.PutBNCtx(ctx)
+
+    err := openssl.MakeAffineECPs(curve.Group, ecPoints, ctx)
+    if err != nil {
+        freePoints(copies)
+        return nil, err
+    }
+    return copies, nil
+}
+
+func freeModBNs(nums []*ModBigNum) {
+    for _, num := range nums {
+        if num != nil {
+            num.Free()
+        }
+    }
+}
+
+func freePoints(points []*Point) {
+    for _, point := range points {
+        if point != nil {
+            point.Free()
+        }
+    }
+}
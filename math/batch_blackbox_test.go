@@ -0,0 +1,292 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package math_test
+
+import (
+    "bytes"
+    "testing"
+    "github.com/nucypher/goUmbral/math"
+    "github.com/nucypher/goUmbral/openssl"
+)
+
+func TestBatchInvert(t *testing.T) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer curve.Free()
+
+    for _, n := range []int{1, 2, 7} {
+        nums := make([]*math.ModBigNum, n)
+        for i := range nums {
+            nums[i], err = math.GenRandModBN(curve)
+            if err != nil {
+                t.Fatal(err)
+            }
+            defer nums[i].Free()
+        }
+
+        invs, err := math.BatchInvert(nums)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        for i, inv := range invs {
+            expected, err := nums[i].Copy()
+            if err != nil {
+                t.Fatal(err)
+            }
+            defer expected.Free()
+
+            err = expected.Invert(nums[i])
+            if err != nil {
+                t.Error(err)
+            }
+
+            if !inv.Equals(expected) {
+                t.Error("The batch inverse", i, "of", n, "was not equal to the inverse")
+            }
+            inv.Free()
+        }
+    }
+
+    invs, err := math.BatchInvert(nil)
+    if err != nil || len(invs) != 0 {
+        t.Error("An empty batch was not inverted to an empty batch")
+    }
+
+    one, err := math.IntToModBN(1, curve)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer one.Free()
+
+    zero, err := one.Copy()
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer zero.Free()
+
+    err = zero.Sub(zero, one)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    _, err = math.BatchInvert([]*math.ModBigNum{one, zero})
+    if err == nil {
+        t.Error("A batch with zero was inverted")
+    }
+}
+
+func TestBatchToBytes(t *testing.T) {
+    curve, err := openssl.NewCurve(openssl.SECP256R1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer curve.Free()
+
+    points := make([]*math.Point, 5)
+    for i := range points {
+        // Random multiples are not in affine coordinates internally.
+        points[i], err = math.GenRandPoint(curve)
+        if err != nil {
+            t.Fatal(err)
+        }
+        defer points[i].Free()
+
+        scalar, err := math.GenRandModBN(curve)
+        if err != nil {
+            t.Fatal(err)
+        }
+        defer scalar.Free()
+
+        err = points[i].Mul(points[i], scalar)
+        if err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    var expected [][]byte
+    for _, point := range points {
+        data, err := point.ToBytes(true)
+        if err != nil {
+            t.Fatal(err)
+        }
+        expected = append(expected, data)
+    }
+
+    for _, isCompressed := range []bool{true, false} {
+        encoded, err := math.BatchToBytes(points, isCompressed)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        for i, data := range encoded {
+            decoded, err := math.BytesToPoint(data, curve)
+            if err != nil {
+                t.Fatal(err)
+            }
+            defer decoded.Free()
+
+            equal, err := decoded.Equals(points[i])
+            if err != nil {
+                t.Error(err)
+            }
+            if !equal {
+                t.Error("The batch serialization", i, "did not decode to the point")
+            }
+        }
+    }
+
+    // The Points keep their values after the batch normalization.
+    for i, point := range points {
+        data, err := point.ToBytes(true)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if !bytes.Equal(data, expected[i]) {
+            t.Error("The point", i, "was changed by the batch serialization")
+        }
+    }
+}
+
+func benchmarkScalars(b *testing.B, curve *openssl.Curve, n int) []*math.ModBigNum {
+    nums := make([]*math.ModBigNum, n)
+    var err error
+    for i := range nums {
+        nums[i], err = math.GenRandModBN(curve)
+        if err != nil {
+            b.Fatal(err)
+        }
+    }
+    return nums
+}
+
+func BenchmarkBatchInvert(b *testing.B) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        b.Fatal(err)
+    }
+    defer curve.Free()
+
+    nums := benchmarkScalars(b, curve, 32)
+
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        invs, err := math.BatchInvert(nums)
+        if err != nil {
+            b.Fatal(err)
+        }
+        for _, inv := range invs {
+            inv.Free()
+        }
+    }
+}
+
+func BenchmarkInvertEach(b *testing.B) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        b.Fatal(err)
+    }
+    defer curve.Free()
+
+    nums := benchmarkScalars(b, curve, 32)
+
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        for _, num := range nums {
+            inv, err := num.Copy()
+            if err != nil {
+                b.Fatal(err)
+            }
+
+            err = inv.Invert(num)
+            if err != nil {
+                b.Fatal(err)
+            }
+            inv.Free()
+        }
+    }
+}
+
+func benchmarkPoints(b *testing.B, curve *openssl.Curve, n int) []*math.Point {
+    points := make([]*math.Point, n)
+    for i := range points {
+        point, err := math.GenRandPoint(curve)
+        if err != nil {
+            b.Fatal(err)
+        }
+        points[i] = point
+    }
+    return points
+}
+
+// Returns Points which are not in affine coordinates internally.
+func benchmarkProjectivePoints(b *testing.B, points []*math.Point) {
+    for _, point := range points {
+        err := point.Add(point, point)
+        if err != nil {
+            b.Fatal(err)
+        }
+    }
+}
+
+func BenchmarkBatchToBytes(b *testing.B) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        b.Fatal(err)
+    }
+    defer curve.Free()
+
+    points := benchmarkPoints(b, curve, 32)
+
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        b.StopTimer()
+        benchmarkProjectivePoints(b, points)
+        b.StartTimer()
+
+        _, err := math.BatchToBytes(points, true)
+        if err != nil {
+            b.Fatal(err)
+        }
+    }
+}
+
+func BenchmarkToBytesEach(b *testing.B) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        b.Fatal(err)
+    }
+    defer curve.Free()
+
+    points := benchmarkPoints(b, curve, 32)
+
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        b.StopTimer()
+        benchmarkProjectivePoints(b, points)
+        b.StartTimer()
+
+        for _, point := range points {
+            _, err := point.ToBytes(true)
+            if err != nil {
+                b.Fatal(err)
+            }
+        }
+    }
+}
@@ -0,0 +1,296 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package math_test
+
+import (
+    "bytes"
+    "errors"
+    "sync"
+    "testing"
+    "github.com/nucypher/goUmbral/math"
+    "github.com/nucypher/goUmbral/openssl"
+)
+
+// The number of goroutines sharing a curve in the stress tests,
+// which are meant to be run with -race.
+const stressGoroutines = 200
+
+var errMismatch = errors.New("The results of the shared arithmetic do not match")
+
+// Checks g^a * u^b == (g^a) + (u^b) and a * a^-1 == 1 for random a and b.
+func checkSharedArithmetic(params *math.UmbralParameters, one *math.ModBigNum) error {
+    curve := params.Curve
+
+    a, err := math.GenRandModBN(curve)
+    if err != nil {
+        return err
+    }
+    defer a.Free()
+
+    b, err := math.GenRandModBN(curve)
+    if err != nil {
+        return err
+    }
+    defer b.Free()
+
+    ga, err := math.GenRandPoint(curve)
+    if err != nil {
+        return err
+    }
+    defer ga.Free()
+
+    err = ga.Mul(params.G, a)
+    if err != nil {
+        return err
+    }
+
+    ub, err := ga.Copy()
+    if err != nil {
+        return err
+    }
+    defer ub.Free()
+
+    err = ub.Mul(params.U, b)
+    if err != nil {
+        return err
+    }
+
+    sum, err := ga.Copy()
+    if err != nil {
+        return err
+    }
+    defer sum.Free()
+
+    err = sum.Add(ga, ub)
+    if err != nil {
+        return err
+    }
+
+    msm, err := math.MultiScalarMul([]*math.Point{params.G, params.U}, []*math.ModBigNum{a, b})
+    if err != nil {
+        return err
+    }
+    defer msm.Free()
+
+    equal, err := sum.Equals(msm)
+    if err != nil {
+        return err
+    }
+    if !equal {
+        return errMismatch
+    }
+
+    inv := math.NewZeroModBN(curve)
+    defer inv.Free()
+
+    err = inv.Invert(a)
+    if err != nil {
+        return err
+    }
+
+    err = inv.Mul(inv, a)
+    if err != nil {
+        return err
+    }
+    if !inv.Equals(one) {
+        return errMismatch
+    }
+    return nil
+}
+
+func TestConcurrentSharedCurve(t *testing.T) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    params, err := math.NewUmbralParameters(curve)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    err = params.Precompute()
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    one, err := math.IntToModBN(1, curve)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer one.Free()
+
+    start := make(chan struct{})
+    errs := make(chan error, stressGoroutines)
+    var wg sync.WaitGroup
+    for i := 0; i < stressGoroutines; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            <-start
+            for j := 0; j < 2; j++ {
+                err := checkSharedArithmetic(params, one)
+                if err != nil {
+                    errs <- err
+                    return
+                }
+            }
+        }()
+    }
+
+    // The curve stays alive while the parameters and the results use it.
+    curve.Free()
+    close(start)
+
+    wg.Wait()
+    close(errs)
+    for err := range errs {
+        t.Error(err)
+    }
+    params.Free()
+}
+
+// Returns n points that are not in affine coordinates internally,
+// and their serializations, adding u to sum for each of them.
+func nonAffinePoints(sum, u *math.Point, n int) ([]*math.Point, [][]byte, error) {
+    points := make([]*math.Point, n)
+    expected := make([][]byte, n)
+    for i := range points {
+        // A sum of points is not in affine coordinates internally.
+        err := sum.Add(sum, u)
+        if err != nil {
+            return nil, nil, err
+        }
+
+        points[i], err = sum.Copy()
+        if err != nil {
+            return nil, nil, err
+        }
+
+        expected[i], err = points[i].ToBytes(true)
+        if err != nil {
+            return nil, nil, err
+        }
+    }
+    return points, expected, nil
+}
+
+// Points that are only read may be shared, even when they are not
+// in affine coordinates internally and G and U have no tables.
+// The race detector does not see the writes made by OpenSSL, so the
+// serializations are checked instead, on fresh points in every round.
+func TestConcurrentSharedPoints(t *testing.T) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer curve.Free()
+
+    params, err := math.NewUmbralParameters(curve)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer params.Free()
+
+    sum, err := math.GenRandPoint(curve)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer sum.Free()
+
+    for round := 0; round < 100; round++ {
+        points, expected, err := nonAffinePoints(sum, params.U, 256)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        start := make(chan struct{})
+        errs := make(chan error, 8)
+        var wg sync.WaitGroup
+        for i := 0; i < 8; i++ {
+            wg.Add(1)
+            go func() {
+                defer wg.Done()
+                <-start
+                encoded, err := math.BatchToBytes(points, true)
+                if err != nil {
+                    errs <- err
+                    return
+                }
+                for k := range encoded {
+                    if !bytes.Equal(encoded[k], expected[k]) {
+                        errs <- errMismatch
+                        return
+                    }
+                }
+            }()
+        }
+        close(start)
+        wg.Wait()
+        close(errs)
+        for err := range errs {
+            t.Fatal(err)
+        }
+
+        for i, point := range points {
+            data, err := point.ToBytes(true)
+            if err != nil {
+                t.Fatal(err)
+            }
+            if !bytes.Equal(data, expected[i]) {
+                t.Fatal("A shared point was modified")
+            }
+            point.Free()
+        }
+    }
+}
+
+func TestUmbralParametersRetain(t *testing.T) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer curve.Free()
+
+    params, err := math.NewUmbralParameters(curve)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    var wg sync.WaitGroup
+    for i := 0; i < stressGoroutines; i++ {
+        wg.Add(1)
+        go func(params *math.UmbralParameters) {
+            defer wg.Done()
+            defer params.Release()
+
+            _, err := params.U.ToBytes(true)
+            if err != nil {
+                t.Error(err)
+            }
+        }(params.Retain())
+    }
+
+    // Free is idempotent, and the goroutines still hold references.
+    params.Free()
+    params.Free()
+    wg.Wait()
+
+    if params.U.ECPoint != nil {
+        t.Error("U should be freed with the last reference to the parameters")
+    }
+}
@@ -0,0 +1,219 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package math
+
+import (
+    "encoding/binary"
+    "encoding/hex"
+    "encoding/json"
+    "github.com/nucypher/goUmbral/openssl"
+)
+
+// ModBigNums and Points implement encoding.BinaryMarshaler,
+// encoding.TextMarshaler and json.Marshaler with a self-describing encoding:
+// the NID of the curve as a 2 byte big endian integer, followed by
+// the serialization of the ModBigNum or of the compressed Point.
+// The text encoding is the binary encoding in hex, and the JSON encoding
+// is the text encoding as a string.
+//
+// The curve is resolved on decoding with openssl.LookupCurve.
+// ModBigNums and Points may be held by pointer or by value in the structures
+// that are decoded, and must be freed by the owner of the structure.
+// A value must not be copied from a ModBigNum or Point in use, since the copy
+// would share its BIGNUM or EC_POINT.
+
+// The length (in bytes) of the NID of the curve prefixing the encodings.
+const curveTagLength = 2
+
+// Returns the NID of the curve as the prefix of an encoding.
+func curveTag(curve *openssl.Curve) []byte {
+    tag := make([]byte, curveTagLength)
+    binary.BigEndian.PutUint16(tag, uint16(curve.NID))
+    return tag
+}
+
+// Returns the curve named by the prefix of the encoding, and the rest of it.
+func parseCurveTag(data []byte) (*openssl.Curve, []byte, error) {
+    if len(data) < curveTagLength {
+        return nil, nil, &EncodingError{Reason: "The encoding is too short to name a curve"}
+    }
+    nid := binary.BigEndian.Uint16(data[:curveTagLength])
+
+    curve, err := openssl.LookupCurve(int(nid))
+    if err != nil {
+        return nil, nil, err
+    }
+    return curve, data[curveTagLength:], nil
+}
+
+// Returns the text encoding as a JSON string.
+func textToJSON(text []byte, err error) ([]byte, error) {
+    if err != nil {
+        return nil, err
+    }
+    return json.Marshal(string(text))
+}
+
+// Returns the text encoding held by the JSON string.
+func jsonToText(data []byte) ([]byte, error) {
+    var text string
+    err := json.Unmarshal(data, &text)
+    if err != nil {
+        return nil, err
+    }
+    return []byte(text), nil
+}
+
+// Returns the binary encoding held by the hex text encoding.
+func textToBinary(text []byte) ([]byte, error) {
+    data := make([]byte, hex.DecodedLen(len(text)))
+    _, err := hex.Decode(data, text)
+    if err != nil {
+        return nil, &EncodingError{Reason: "The encoding is not valid hex"}
+    }
+    return data, nil
+}
+
+// Returns the binary encoding in hex.
+func binaryToText(data []byte, err error) ([]byte, error) {
+    if err != nil {
+        return nil, err
+    }
+    text := make([]byte, hex.EncodedLen(len(data)))
+    hex.Encode(text, data)
+    return text, nil
+}
+
+// ModBigNum.MarshalBinary() returns the NID of the curve followed by
+// the serialization of the ModBigNum.
+func (m *ModBigNum) MarshalBinary() ([]byte, error) {
+    data, err := m.Bytes()
+    if err != nil {
+        return nil, err
+    }
+    return append(curveTag(m.Curve), data...), nil
+}
+
+// ModBigNum.UnmarshalBinary() sets the ModBigNum from its binary encoding,
+// and frees the BIGNUM it held before.
+//
+// A ModBigNum that was not allocated by this package, such as a field
+// of a struct, has no finalizer and must be freed by the calling function.
+func (m *ModBigNum) UnmarshalBinary(data []byte) error {
+    curve, data, err := parseCurveTag(data)
+    if err != nil {
+        return err
+    }
+
+    decoded, err := BytesToModBN(data, curve)
+    if err != nil {
+        return err
+    }
+    // Swap the BIGNUMs and the references to the curves, so that m keeps
+    // its own finalizer, if any, and the old BIGNUM is freed with decoded.
+    m.Bignum, decoded.Bignum = decoded.Bignum, m.Bignum
+    m.Curve, decoded.Curve = decoded.Curve, m.Curve
+    m.curveRef, decoded.curveRef = decoded.curveRef, m.curveRef
+    decoded.Free()
+    return nil
+}
+
+func (m *ModBigNum) MarshalText() ([]byte, error) {
+    return binaryToText(m.MarshalBinary())
+}
+
+func (m *ModBigNum) UnmarshalText(text []byte) error {
+    data, err := textToBinary(text)
+    if err != nil {
+        return err
+    }
+    return m.UnmarshalBinary(data)
+}
+
+func (m *ModBigNum) MarshalJSON() ([]byte, error) {
+    return textToJSON(m.MarshalText())
+}
+
+func (m *ModBigNum) UnmarshalJSON(data []byte) error {
+    text, err := jsonToText(data)
+    if err != nil {
+        return err
+    }
+    return m.UnmarshalText(text)
+}
+
+// Point.MarshalBinary() returns the NID of the curve followed by
+// the compressed serialization of the Point.
+func (m *Point) MarshalBinary() ([]byte, error) {
+    data, err := m.ToBytes(true)
+    if err != nil {
+        return nil, err
+    }
+    return append(curveTag(m.Curve), data...), nil
+}
+
+// Point.UnmarshalBinary() sets the Point from its binary encoding,
+// and frees the EC_POINT it held before.
+//
+// A Point that was not allocated by this package, such as a field
+// of a struct, has no finalizer and must be freed by the calling function.
+//
+// The serialization of the Point may be compressed or uncompressed.
+func (m *Point) UnmarshalBinary(data []byte) error {
+    curve, data, err := parseCurveTag(data)
+    if err != nil {
+        return err
+    }
+
+    decoded, err := BytesToPoint(data, curve)
+    if err != nil {
+        return err
+    }
+    // Swap the EC_POINTs and the references to the curves, so that m keeps
+    // its own finalizer, if any, and the old EC_POINT is freed with decoded.
+    // The table of multiples of the old EC_POINT is dropped.
+    m.ECPoint, decoded.ECPoint = decoded.ECPoint, m.ECPoint
+    m.Curve, decoded.Curve = decoded.Curve, m.Curve
+    m.curveRef, decoded.curveRef = decoded.curveRef, m.curveRef
+    m.table = nil
+    decoded.Free()
+    return nil
+}
+
+func (m *Point) MarshalText() ([]byte, error) {
+    return binaryToText(m.MarshalBinary())
+}
+
+func (m *Point) UnmarshalText(text []byte) error {
+    data, err := textToBinary(text)
+    if err != nil {
+        return err
+    }
+    return m.UnmarshalBinary(data)
+}
+
+func (m *Point) MarshalJSON() ([]byte, error) {
+    return textToJSON(m.MarshalText())
+}
+
+func (m *Point) UnmarshalJSON(data []byte) error {
+    text, err := jsonToText(data)
+    if err != nil {
+        return err
+    }
+    return m.UnmarshalText(text)
+}
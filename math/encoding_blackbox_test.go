@@ -0,0 +1,246 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package math_test
+
+import (
+    "bytes"
+    "encoding/gob"
+    "encoding/json"
+    "errors"
+    "testing"
+    "github.com/nucypher/goUmbral/math"
+    "github.com/nucypher/goUmbral/openssl"
+)
+
+type encodedItems struct {
+    Scalar *math.ModBigNum
+    Point *math.Point
+}
+
+func newEncodedItems(t *testing.T, curve *openssl.Curve) *encodedItems {
+    scalar, err := math.GenRandModBN(curve)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    point, err := math.GenRandPoint(curve)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return &encodedItems{Scalar: scalar, Point: point}
+}
+
+func (m *encodedItems) equals(t *testing.T, other *encodedItems) bool {
+    if !m.Scalar.Curve.Equals(other.Scalar.Curve) || !m.Point.Curve.Equals(other.Point.Curve) {
+        return false
+    }
+    equal, err := m.Point.Equals(other.Point)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return equal && m.Scalar.Equals(other.Scalar)
+}
+
+func (m *encodedItems) Free() {
+    m.Scalar.Free()
+    m.Point.Free()
+}
+
+func TestEncodingRoundTrip(t *testing.T) {
+    for _, nid := range []int{openssl.SECP256K1, openssl.SECP256R1, openssl.SECP384R1} {
+        curve, err := openssl.LookupCurve(nid)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        items := newEncodedItems(t, curve)
+        defer items.Free()
+
+        t.Run("json", func(t *testing.T) {
+            data, err := json.Marshal(items)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            var decoded encodedItems
+            err = json.Unmarshal(data, &decoded)
+            if err != nil {
+                t.Fatal(err)
+            }
+            defer decoded.Free()
+
+            if !items.equals(t, &decoded) {
+                t.Error("The decoded JSON is not equal to the original")
+            }
+        })
+
+        t.Run("gob", func(t *testing.T) {
+            var buf bytes.Buffer
+            err := gob.NewEncoder(&buf).Encode(items)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            var decoded encodedItems
+            err = gob.NewDecoder(&buf).Decode(&decoded)
+            if err != nil {
+                t.Fatal(err)
+            }
+            defer decoded.Free()
+
+            if !items.equals(t, &decoded) {
+                t.Error("The decoded gob is not equal to the original")
+            }
+        })
+
+        t.Run("text", func(t *testing.T) {
+            text, err := items.Point.MarshalText()
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            // Decoding into a Point that is in use replaces it.
+            point, err := math.GenRandPoint(curve)
+            if err != nil {
+                t.Fatal(err)
+            }
+            defer point.Free()
+
+            err = point.UnmarshalText(text)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            equal, err := point.Equals(items.Point)
+            if err != nil {
+                t.Fatal(err)
+            }
+            if !equal {
+                t.Error("The decoded text is not equal to the original")
+            }
+        })
+    }
+}
+
+func TestEncodingEmbeddedFields(t *testing.T) {
+    curve, err := openssl.LookupCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    items := newEncodedItems(t, curve)
+    defer items.Free()
+
+    // The encoding is the same whether the fields are held by pointer
+    // or by value.
+    data, err := json.Marshal(&struct {
+        Tag string
+        K *math.ModBigNum
+        P *math.Point
+    }{Tag: "key", K: items.Scalar, P: items.Point})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    var decoded struct {
+        Tag string
+        K math.ModBigNum
+        P math.Point
+    }
+    defer decoded.K.Free()
+    defer decoded.P.Free()
+
+    // Decoding again replaces the values decoded the first time.
+    for i := 0; i < 2; i++ {
+        err = json.Unmarshal(data, &decoded)
+        if err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    if decoded.Tag != "key" || !items.equals(t, &encodedItems{Scalar: &decoded.K, Point: &decoded.P}) {
+        t.Error("The decoded JSON is not equal to the original")
+    }
+}
+
+func TestEncodingRegisteredCurve(t *testing.T) {
+    curve, err := openssl.NewCurve(openssl.SECP256R1)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // Restore the registered P-256 curve afterwards, for the other tests.
+    previous, err := openssl.LookupCurve(openssl.SECP256R1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    previous.Retain()
+    defer func() {
+        openssl.RegisterCurve(previous)
+        previous.Release()
+    }()
+
+    openssl.RegisterCurve(curve)
+    // The registry holds its own reference.
+    curve.Free()
+
+    items := newEncodedItems(t, curve)
+    defer items.Free()
+
+    data, err := items.Scalar.MarshalBinary()
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    var decoded math.ModBigNum
+    err = decoded.UnmarshalBinary(data)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer decoded.Free()
+
+    if decoded.Curve != curve {
+        t.Error("The decoded ModBigNum does not use the registered curve")
+    }
+}
+
+func TestMarshalErrors(t *testing.T) {
+    tests := []struct {
+        name string
+        data []byte
+        target error
+    }{
+        {"no curve", []byte{2}, math.ErrInvalidEncoding},
+        {"unknown curve", []byte{0, 1, 2, 3}, math.ErrUnsupportedCurve},
+        {"no point", []byte{byte(openssl.SECP256K1 >> 8), byte(openssl.SECP256K1 & 0xff)},
+            math.ErrInvalidEncoding},
+    }
+
+    for _, test := range tests {
+        var point math.Point
+        err := point.UnmarshalBinary(test.data)
+        if !errors.Is(err, test.target) {
+            t.Errorf("%s: got %v, expected %v", test.name, err, test.target)
+        }
+    }
+
+    var scalar math.ModBigNum
+    err := scalar.UnmarshalText([]byte("not hex"))
+    if !errors.Is(err, math.ErrInvalidEncoding) {
+        t.Error("Got:", err, "Expected:", math.ErrInvalidEncoding)
+    }
+}
@@ -0,0 +1,54 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package math
+
+import (
+    "errors"
+    "github.com/nucypher/goUmbral/openssl"
+)
+
+// Errors that callers may check for with errors.Is.
+//
+// Errors about the input, such as a malformed serialization, match one of
+// these, while internal failures are returned as an *openssl.OpenSSLError.
+var (
+    // Returned when the operands of an operation are on different curves.
+    ErrCurveMismatch = errors.New("The curves are not equal")
+
+    // Returned for coordinates that do not describe a point of the curve.
+    ErrPointNotOnCurve = openssl.ErrPointNotOnCurve
+
+    // Returned for a malformed serialization of a Point or a ModBigNum.
+    ErrInvalidEncoding = openssl.ErrInvalidEncoding
+
+    // Returned for a bignum that is not in [1, n - 1] for the order n of the curve.
+    ErrScalarOutOfRange = errors.New("The bignum is not within the order of the curve")
+
+    // Returned when no point of the curve could be derived from a hash.
+    ErrNoPointFound = errors.New("Could not hash input into the curve")
+
+    // Returned for the square root of a ModBigNum that is not a square.
+    ErrNotASquare = openssl.ErrNotASquare
+
+    // Returned for a curve that is not supported by an operation.
+    ErrUnsupportedCurve = openssl.ErrUnsupportedCurve
+)
+
+// Represents a malformed serialization, with the reason it was rejected.
+//
+// An EncodingError matches ErrInvalidEncoding with errors.Is.
+type EncodingError = openssl.EncodingError
@@ -0,0 +1,166 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package math_test
+
+import (
+    "errors"
+    "testing"
+    "github.com/nucypher/goUmbral/math"
+    "github.com/nucypher/goUmbral/openssl"
+)
+
+func TestEncodingErrors(t *testing.T) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer curve.Free()
+
+    pointSize := math.PointLength(curve, true)
+    bnSize := math.ExpectedBytesLength(curve)
+    compressed := make([]byte, pointSize)
+    compressed[0] = 2
+
+    // x = 5 has no y on secp256k1, since 5^3 + 7 is not a square.
+    noY := make([]byte, pointSize)
+    noY[0] = 2
+    noY[pointSize - 1] = 5
+
+    // (1, 1) is not on secp256k1.
+    offCurve := make([]byte, 2 * pointSize - 1)
+    offCurve[0] = 4
+    offCurve[pointSize - 1] = 1
+    offCurve[2 * pointSize - 2] = 1
+
+
+    tests := []struct {
+        name string
+        parse func() error
+        target error
+    }{
+        {"empty point", func() error {
+            _, err := math.BytesToPoint(nil, curve)
+            return err
+        }, math.ErrInvalidEncoding},
+        {"short point", func() error {
+            _, err := math.BytesToPoint(compressed[:pointSize - 1], curve)
+            return err
+        }, math.ErrInvalidEncoding},
+        {"bad prefix", func() error {
+            _, err := math.BytesToPoint(append([]byte{5}, compressed[1:]...), curve)
+            return err
+        }, math.ErrInvalidEncoding},
+        {"x without y", func() error {
+            _, err := math.BytesToPoint(noY, curve)
+            return err
+        }, math.ErrPointNotOnCurve},
+        {"point off the curve", func() error {
+            _, err := math.BytesToPoint(offCurve, curve)
+            return err
+        }, math.ErrPointNotOnCurve},
+        {"short bignum", func() error {
+            _, err := math.BytesToModBN(make([]byte, bnSize - 1), curve)
+            return err
+        }, math.ErrInvalidEncoding},
+        {"zero bignum", func() error {
+            _, err := math.BytesToModBN(make([]byte, bnSize), curve)
+            return err
+        }, math.ErrScalarOutOfRange},
+        {"zero int", func() error {
+            _, err := math.IntToModBN(0, curve)
+            return err
+        }, math.ErrScalarOutOfRange},
+    }
+
+    for _, test := range tests {
+        err := test.parse()
+        if !errors.Is(err, test.target) {
+            t.Errorf("%s: got %v, expected %v", test.name, err, test.target)
+        }
+    }
+}
+
+func TestCurveMismatchErrors(t *testing.T) {
+    curve1, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer curve1.Free()
+
+    curve2, err := openssl.NewCurve(openssl.SECP256R1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer curve2.Free()
+
+    bn1, err := math.GenRandModBN(curve1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer bn1.Free()
+
+    bn2, err := math.GenRandModBN(curve2)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer bn2.Free()
+
+    err = bn1.Add(bn1, bn2)
+    if !errors.Is(err, math.ErrCurveMismatch) {
+        t.Error("Got:", err, "Expected:", math.ErrCurveMismatch)
+    }
+
+    p1, err := math.GenRandPoint(curve1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer p1.Free()
+
+    p2, err := math.GenRandPoint(curve2)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer p2.Free()
+
+    err = p1.Add(p1, p2)
+    if !errors.Is(err, math.ErrCurveMismatch) {
+        t.Error("Got:", err, "Expected:", math.ErrCurveMismatch)
+    }
+}
+
+func TestOpenSSLErrorAs(t *testing.T) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer curve.Free()
+
+    // x = 5 has no y on secp256k1.
+    data := make([]byte, math.PointLength(curve, true))
+    data[0] = 3
+    data[len(data) - 1] = 5
+
+    _, err = math.BytesToPoint(data, curve)
+
+    var sslErr *openssl.OpenSSLError
+    if !errors.As(err, &sslErr) {
+        t.Fatal("Got:", err, "Expected an *openssl.OpenSSLError")
+    }
+    if sslErr.Library == "" || sslErr.Reason == "" || sslErr.ReasonCode() == 0 {
+        t.Error("The library and reason of the error are not set:", sslErr)
+    }
+}
@@ -0,0 +1,293 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package math
+
+import (
+    "math/big"
+    "math/bits"
+)
+
+// Represents a prime field GF(p) with the constants needed for
+// Montgomery arithmetic on fixed-size limbs.
+//
+// Unlike OpenSSL BIGNUMs and Go big.Ints, the fieldElements of a primeField
+// always have the same number of limbs, and every operation on them runs
+// in constant time with respect to their values. It is used where the
+// inputs may be secret, such as in HashToCurve.
+
+type primeField struct {
+    pBig *big.Int
+    p []uint64
+    n int
+    // -p^-1 mod 2^64
+    pInv uint64
+    // R^2 mod p where R = 2^(64n), used to convert into Montgomery form.
+    r2 fieldElement
+    // R mod p, the Montgomery form of 1.
+    one fieldElement
+    // The size (in bytes) of p.
+    size int
+}
+
+// Represents an element of a primeField in Montgomery form,
+// as little endian 64 bit limbs.
+
+type fieldElement []uint64
+
+// The number of limbs of the field of P-384, the largest supported curve,
+// so that the temporaries of the arithmetic are not allocated on the heap.
+const maxFieldLimbs = 6
+
+// Returns the primeField of the odd prime p.
+func newPrimeField(p *big.Int) *primeField {
+    n := (p.BitLen() + 63) / 64
+    f := &primeField{pBig: new(big.Int).Set(p), p: bigToLimbs(p, n), n: n,
+        size: (p.BitLen() + 7) / 8}
+
+    // Newton's iteration for the inverse of p modulo 2^64.
+    inv := uint64(1)
+    for i := 0; i < 6; i++ {
+        inv *= 2 - f.p[0] * inv
+    }
+    f.pInv = -inv
+
+    r := new(big.Int).Lsh(big.NewInt(1), uint(64 * n))
+    f.one = bigToLimbs(new(big.Int).Mod(r, p), n)
+    f.r2 = bigToLimbs(new(big.Int).Mod(new(big.Int).Mul(r, r), p), n)
+    return f
+}
+
+// Returns the little endian limbs of x, which must fit in n limbs.
+func bigToLimbs(x *big.Int, n int) []uint64 {
+    buf := make([]byte, 8 * n)
+    data := x.Bytes()
+    copy(buf[len(buf) - len(data):], data)
+
+    limbs := make([]uint64, n)
+    for i := range limbs {
+        for _, b := range buf[8 * (n - 1 - i):8 * (n - i)] {
+            limbs[i] = limbs[i] << 8 | uint64(b)
+        }
+    }
+    return limbs
+}
+
+func (f *primeField) newElement() fieldElement {
+    return make(fieldElement, f.n)
+}
+
+// Returns the element of x, which must be in [0, p).
+// It is only meant for public constants.
+func (f *primeField) fromBig(x *big.Int) fieldElement {
+    z := f.newElement()
+    f.mul(z, bigToLimbs(new(big.Int).Mod(x, f.pBig), f.n), f.r2)
+    return z
+}
+
+// Returns the element of the big endian bytes, which must not be
+// longer than the limbs of the field. Values in [p, 2^(64n)) are reduced,
+// which is only exact if they are below 2p.
+func (f *primeField) fromBytes(data []byte) fieldElement {
+    buf := make([]byte, 8 * f.n)
+    copy(buf[len(buf) - len(data):], data)
+
+    x := f.newElement()
+    for i := range x {
+        for _, b := range buf[8 * (f.n - 1 - i):8 * (f.n - i)] {
+            x[i] = x[i] << 8 | uint64(b)
+        }
+    }
+    f.reduceOnce(x, 0)
+
+    z := f.newElement()
+    f.mul(z, x, f.r2)
+    return z
+}
+
+// Returns x as a Go big.Int.
+func (f *primeField) toBig(x fieldElement) *big.Int {
+    return new(big.Int).SetBytes(f.bytes(f.fromMont(x)))
+}
+
+// Returns the big endian bytes of the limbs, left padded to the field size.
+func (f *primeField) bytes(limbs []uint64) []byte {
+    buf := make([]byte, f.size)
+    f.putBytes(buf, limbs)
+    return buf
+}
+
+// Writes the big endian bytes of the limbs, which must be below p,
+// to the f.size bytes of buf.
+func (f *primeField) putBytes(buf []byte, limbs []uint64) {
+    for i := 0; i < f.size; i++ {
+        buf[f.size - 1 - i] = byte(limbs[i / 8] >> uint(8 * (i % 8)))
+    }
+}
+
+// Returns x converted out of Montgomery form.
+func (f *primeField) fromMont(x fieldElement) []uint64 {
+    one := make([]uint64, f.n)
+    one[0] = 1
+    z := f.newElement()
+    f.mul(z, x, one)
+    return z
+}
+
+// Subtracts p from the value carry * 2^(64n) + x if it is not below p.
+func (f *primeField) reduceOnce(x []uint64, carry uint64) {
+    var buf [maxFieldLimbs]uint64
+    reduced := buf[:f.n]
+    var borrow uint64
+    for i := range x {
+        reduced[i], borrow = bits.Sub64(x[i], f.p[i], borrow)
+    }
+    // Keep x only if it was below p, i.e. there was a borrow and no carry.
+    keep := borrow &^ carry
+    f.cmov(x, reduced, x, keep)
+}
+
+// Sets z = c ? b : a, where c must be 0 or 1.
+func (f *primeField) cmov(z, a, b []uint64, c uint64) {
+    mask := -c
+    for i := range z {
+        z[i] = a[i] ^ (mask & (a[i] ^ b[i]))
+    }
+}
+
+// Sets z = x + y.
+func (f *primeField) add(z, x, y fieldElement) {
+    var carry uint64
+    for i := range z {
+        z[i], carry = bits.Add64(x[i], y[i], carry)
+    }
+    f.reduceOnce(z, carry)
+}
+
+// Sets z = x - y.
+func (f *primeField) sub(z, x, y fieldElement) {
+    var borrow uint64
+    for i := range z {
+        z[i], borrow = bits.Sub64(x[i], y[i], borrow)
+    }
+
+    // Add p back if the subtraction underflowed.
+    mask := -borrow
+    var carry uint64
+    for i := range z {
+        z[i], carry = bits.Add64(z[i], f.p[i] & mask, carry)
+    }
+}
+
+// Sets z = -x.
+func (f *primeField) neg(z, x fieldElement) {
+    f.sub(z, f.newElement(), x)
+}
+
+// Sets z = x * y with the CIOS Montgomery multiplication.
+func (f *primeField) mul(z, x, y fieldElement) {
+    n := f.n
+    var buf [maxFieldLimbs + 2]uint64
+    t := buf[:n + 2]
+
+    for i := 0; i < n; i++ {
+        // t += x * y[i]
+        var c uint64
+        for j := 0; j < n; j++ {
+            hi, lo := bits.Mul64(x[j], y[i])
+            var cc uint64
+            lo, cc = bits.Add64(lo, t[j], 0)
+            hi += cc
+            lo, cc = bits.Add64(lo, c, 0)
+            hi += cc
+            t[j], c = lo, hi
+        }
+        var cc uint64
+        t[n], cc = bits.Add64(t[n], c, 0)
+        t[n + 1] = cc
+
+        // t = (t + m * p) / 2^64, which is exact for this m.
+        m := t[0] * f.pInv
+        hi, lo := bits.Mul64(m, f.p[0])
+        _, cc = bits.Add64(lo, t[0], 0)
+        c = hi + cc
+        for j := 1; j < n; j++ {
+            hi, lo := bits.Mul64(m, f.p[j])
+            lo, cc = bits.Add64(lo, t[j], 0)
+            hi += cc
+            lo, cc = bits.Add64(lo, c, 0)
+            hi += cc
+            t[j - 1], c = lo, hi
+        }
+        t[n - 1], cc = bits.Add64(t[n], c, 0)
+        t[n] = t[n + 1] + cc
+    }
+
+    f.reduceOnce(t[:n], t[n])
+    copy(z, t[:n])
+}
+
+// Sets z = x^2.
+func (f *primeField) square(z, x fieldElement) {
+    f.mul(z, x, x)
+}
+
+// Sets z = x^e for the public exponent e.
+//
+// The running time depends on e but not on x.
+func (f *primeField) exp(z, x fieldElement, e *big.Int) {
+    result := f.newElement()
+    copy(result, f.one)
+    base := f.newElement()
+    copy(base, x)
+
+    for i := e.BitLen() - 1; i >= 0; i-- {
+        f.square(result, result)
+        if e.Bit(i) == 1 {
+            f.mul(result, result, base)
+        }
+    }
+    copy(z, result)
+}
+
+// Sets z = 1 / x, or z = 0 if x is 0.
+func (f *primeField) inv(z, x fieldElement) {
+    f.exp(z, x, new(big.Int).Sub(f.pBig, big.NewInt(2)))
+}
+
+// Returns 1 if x is 0, and 0 otherwise.
+func (f *primeField) isZero(x fieldElement) uint64 {
+    var acc uint64
+    for _, limb := range x {
+        acc |= limb
+    }
+    // (acc | -acc) has its top bit set unless acc is 0.
+    return 1 ^ ((acc | -acc) >> 63)
+}
+
+// Returns 1 if x == y, and 0 otherwise.
+func (f *primeField) equal(x, y fieldElement) uint64 {
+    diff := f.newElement()
+    for i := range diff {
+        diff[i] = x[i] ^ y[i]
+    }
+    return f.isZero(diff)
+}
+
+// Returns the sign of x as defined by RFC 9380, i.e. its parity.
+func (f *primeField) sgn0(x fieldElement) uint64 {
+    return f.fromMont(x)[0] & 1
+}
@@ -0,0 +1,100 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package math
+
+import (
+    "crypto/subtle"
+)
+
+// Represents the multiples of a fixed Point, from which its product with
+// a scalar is computed with one lookup and one addition per 4 bit window
+// of the scalar, instead of the doublings and additions of a generic
+// multiplication.
+//
+// The lookups scan every entry of a window and the additions are complete,
+// so the running time does not depend on the scalar.
+
+type fixedBaseTable struct {
+    arith *curveArithmetic
+    // windows[i][j] is j * 16^i times the fixed Point, for j in [0, 16).
+    windows [][]projectivePoint
+}
+
+// Returns the fixedBaseTable of the Point, which may be freed afterwards.
+func newFixedBaseTable(base *Point) (*fixedBaseTable, error) {
+    arith, err := getCurveArithmetic(base.Curve)
+    if err != nil {
+        return nil, err
+    }
+
+    p, err := arith.fromPoint(base)
+    if err != nil {
+        return nil, err
+    }
+
+    windows := make([][]projectivePoint, 2 * ExpectedBytesLength(base.Curve))
+    for i := range windows {
+        window := make([]projectivePoint, 16)
+        window[0] = arith.infinity()
+        window[1] = p
+        for j := 2; j < len(window); j++ {
+            window[j] = arith.add(window[j - 1], p)
+        }
+        windows[i] = window
+
+        // 16^(i+1) * P = 15 * 16^i * P + 16^i * P
+        p = arith.add(window[15], p)
+    }
+    return &fixedBaseTable{arith: arith, windows: windows}, nil
+}
+
+// Returns the product of the fixed Point with the scalar,
+// or nil if the product is the point at infinity.
+//
+// The Point must be freed by the calling function.
+func (m *fixedBaseTable) mul(scalar *ModBigNum) (*Point, error) {
+    data, err := scalar.Bytes()
+    if err != nil {
+        return nil, err
+    }
+    // Do not leave a copy of the secret scalar behind.
+    defer func() {
+        for i := range data {
+            data[i] = 0
+        }
+    }()
+    arith := m.arith
+
+    // The additions reuse the same temporaries, which stay on the stack.
+    var scratch addScratch
+    result := arith.infinity()
+    selected := arith.infinity()
+    for i, window := range m.windows {
+        // The windows start from the least significant nibble.
+        digit := int32(data[len(data) - 1 - i / 2] >> uint(4 * (i % 2))) & 0xf
+
+        for j, point := range window {
+            arith.cmov(&selected, point, uint64(subtle.ConstantTimeEq(int32(j), digit)))
+        }
+        arith.addTo(&result, result, selected, &scratch)
+    }
+
+    if arith.field.isZero(result.z) == 1 {
+        return nil, nil
+    }
+    return arith.toPoint(result, scalar.Curve)
+}
@@ -0,0 +1,101 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package math
+
+import (
+    "crypto/sha256"
+    "crypto/sha512"
+    "hash"
+    "golang.org/x/crypto/blake2b"
+    "golang.org/x/crypto/sha3"
+)
+
+// Represents the hash function from which the values of a set of
+// UmbralParameters are derived.
+//
+// BLAKE2b512 is the default, as used by pyUmbral.
+
+type HashFunction int
+
+const (
+    BLAKE2b512 HashFunction = iota
+    SHA256
+    SHA512
+    SHA3_256
+    Keccak256
+)
+
+// Returns a new hash.Hash computing the hash function.
+func (m HashFunction) New() hash.Hash {
+    switch m {
+    case SHA256:
+        return sha256.New()
+    case SHA512:
+        return sha512.New()
+    case SHA3_256:
+        return sha3.New256()
+    case Keccak256:
+        return sha3.NewLegacyKeccak256()
+    default:
+        // blake2b.New512 only fails for keys longer than 64 bytes.
+        h, _ := blake2b.New512(nil)
+        return h
+    }
+}
+
+// Returns the size (in bytes) of a digest of the hash function.
+func (m HashFunction) Size() int {
+    return m.New().Size()
+}
+
+// Returns the digest of the data.
+func (m HashFunction) Sum(data []byte) []byte {
+    h := m.New()
+    h.Write(data)
+    return h.Sum(nil)
+}
+
+func (m HashFunction) String() string {
+    switch m {
+    case SHA256:
+        return "SHA-256"
+    case SHA512:
+        return "SHA-512"
+    case SHA3_256:
+        return "SHA3-256"
+    case Keccak256:
+        return "Keccak-256"
+    default:
+        return "BLAKE2b-512"
+    }
+}
+
+// The domain separation tag of the expand_message_xmd of HashFunction.expand().
+var expandDST = []byte("NuCypher/goUmbral/expand")
+
+// Returns at least length bytes derived from the data: its digest if it is
+// long enough, and otherwise the length bytes of expand_message_xmd
+// (RFC 9380, section 5.3.1) with the hash function.
+//
+// With BLAKE2b-512 and SHA-512, this is always the digest alone.
+func (m HashFunction) expand(data []byte, length int) ([]byte, error) {
+    digest := m.Sum(data)
+    if len(digest) >= length {
+        return digest, nil
+    }
+    return expandMessageXMD(m.New, data, expandDST, length)
+}
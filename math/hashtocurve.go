@@ -0,0 +1,399 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package math
+
+import (
+    "crypto/sha256"
+    "crypto/sha512"
+    "errors"
+    "hash"
+    "math/big"
+    "sync"
+    "github.com/nucypher/goUmbral/openssl"
+)
+
+// Represents an RFC 9380 hash_to_curve suite: expand_message_xmd with
+// a hash function, the simplified SWU map onto the curve, or onto
+// a curve isogenous to it, and complete point addition on the curve.
+//
+// All the arithmetic on the field elements runs in constant time.
+
+type hashToCurveSuite struct {
+    field *primeField
+    newHash func() hash.Hash
+    // The length (in bytes) of the data reduced to each field element.
+    l int
+    // 2^(64n) mod p, to reduce data longer than the limbs of the field.
+    wide fieldElement
+    // The constants of the simplified SWU map onto y^2 = x^3 + A*x + B.
+    z, swuA, swuB fieldElement
+    // (p - 3) / 4 and sqrt(-Z), for sqrt_ratio with p = 3 mod 4.
+    c1 *big.Int
+    c2 fieldElement
+    // The coefficients of the isogeny map from the lowest degree
+    // to the highest, or nil if the map is directly onto the curve.
+    xNum, xDen, yNum, yDen []fieldElement
+    // The arithmetic of the Points of the curve.
+    arith *curveArithmetic
+}
+
+// The constants of the 3-isogeny map from the curve
+// y^2 = x^3 + A'*x + B' to secp256k1, from RFC 9380, appendix E.1.
+const (
+    secp256k1IsoA = "3f8731abdd661adca08a5558f0f5d272e953d363cb6f0e5d405447c01a444533"
+    secp256k1IsoB = "6eb"
+)
+
+var secp256k1IsoXNum = []string{
+    "8e38e38e38e38e38e38e38e38e38e38e38e38e38e38e38e38e38e38daaaaa8c7",
+    "7d3d4c80bc321d5b9f315cea7fd44c5d595d2fc0bf63b92dfff1044f17c6581",
+    "534c328d23f234e6e2a413deca25caece4506144037c40314ecbd0b53d9dd262",
+    "8e38e38e38e38e38e38e38e38e38e38e38e38e38e38e38e38e38e38daaaaa88c",
+}
+
+var secp256k1IsoXDen = []string{
+    "d35771193d94918a9ca34ccbb7b640dd86cd409542f8487d9fe6b745781eb49b",
+    "edadc6f64383dc1df7c4b2d51b54225406d36b641f5e41bbc52a56612a8c6d14",
+    "1",
+}
+
+var secp256k1IsoYNum = []string{
+    "4bda12f684bda12f684bda12f684bda12f684bda12f684bda12f684b8e38e23c",
+    "c75e0c32d5cb7c0fa9d0a54b12a0a6d5647ab046d686da6fdffc90fc201d71a3",
+    "29a6194691f91a73715209ef6512e576722830a201be2018a765e85a9ecee931",
+    "2f684bda12f684bda12f684bda12f684bda12f684bda12f684bda12f38e38d84",
+}
+
+var secp256k1IsoYDen = []string{
+    "fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffff93b",
+    "7a06534bb8bdb49fd5e9e6632722c2989467c1bfc8e8d978dfb425d2685c2573",
+    "6484aa716545ca2cf3a70c3fa8fe337e0a3d21162f0d6299a7bf8192bfd2a76f",
+    "1",
+}
+
+var hashToCurveSuites = struct {
+    sync.Mutex
+    byNID map[int]*hashToCurveSuite
+}{byNID: make(map[int]*hashToCurveSuite)}
+
+// Hashes the message to a Point of the curve, as defined by the RFC 9380
+// suites P256_XMD:SHA-256_SSWU_RO_, P384_XMD:SHA-384_SSWU_RO_ and
+// secp256k1_XMD:SHA-256_SSWU_RO_. The domain separation tag dst must not
+// be empty and should be unique to the application and purpose.
+//
+// Unlike UnsafeHashToPoint, the mapping runs in constant time,
+// so the message may be secret.
+func HashToCurve(msg, dst []byte, curve *openssl.Curve) (*Point, error) {
+    if len(dst) == 0 {
+        return nil, errors.New("The domain separation tag must not be empty")
+    }
+
+    suite, err := getHashToCurveSuite(curve)
+    if err != nil {
+        return nil, err
+    }
+
+    uniform, err := expandMessageXMD(suite.newHash, msg, dst, 2 * suite.l)
+    if err != nil {
+        return nil, err
+    }
+
+    x0, y0 := suite.mapToCurve(suite.hashToField(uniform[:suite.l]))
+    x1, y1 := suite.mapToCurve(suite.hashToField(uniform[suite.l:]))
+
+    // The cofactor of all the supported curves is 1.
+    arith := suite.arith
+    return arith.toPoint(arith.add(arith.fromAffine(x0, y0), arith.fromAffine(x1, y1)), curve)
+}
+
+// Returns the hash_to_curve suite of the curve, building it on first use.
+func getHashToCurveSuite(curve *openssl.Curve) (*hashToCurveSuite, error) {
+    hashToCurveSuites.Lock()
+    defer hashToCurveSuites.Unlock()
+
+    suite, ok := hashToCurveSuites.byNID[curve.NID]
+    if ok {
+        return suite, nil
+    }
+
+    suite, err := newHashToCurveSuite(curve)
+    if err != nil {
+        return nil, err
+    }
+    hashToCurveSuites.byNID[curve.NID] = suite
+    return suite, nil
+}
+
+func newHashToCurveSuite(curve *openssl.Curve) (*hashToCurveSuite, error) {
+    suite := &hashToCurveSuite{}
+    var z int64
+
+    switch curve.NID {
+    case openssl.SECP256R1:
+        suite.newHash, suite.l, z = sha256.New, 48, -10
+    case openssl.SECP384R1:
+        suite.newHash, suite.l, z = sha512.New384, 72, -12
+    case openssl.SECP256K1:
+        suite.newHash, suite.l, z = sha256.New, 48, -11
+    default:
+        return nil, ErrUnsupportedCurve
+    }
+
+    pBN, aBN, bBN, err := openssl.GetECGroupCurve(curve.Group)
+    if err != nil {
+        return nil, err
+    }
+    defer openssl.FreeBigNum(pBN)
+    defer openssl.FreeBigNum(aBN)
+    defer openssl.FreeBigNum(bBN)
+
+    p, err := openssl.BNToBigInt(pBN)
+    if err != nil {
+        return nil, err
+    }
+
+    b, err := openssl.BNToBigInt(bBN)
+    if err != nil {
+        return nil, err
+    }
+
+    suite.arith, err = getCurveArithmetic(curve)
+    if err != nil {
+        return nil, err
+    }
+
+    f := suite.arith.field
+    suite.field = f
+    suite.wide = f.fromBig(new(big.Int).Lsh(big.NewInt(1), uint(64 * f.n)))
+    suite.z = f.fromBig(big.NewInt(z))
+
+    if curve.NID == openssl.SECP256K1 {
+        // The simplified SWU map needs A != 0, so it maps onto
+        // an isogenous curve instead.
+        suite.swuA = f.fromBig(hexToBig(secp256k1IsoA))
+        suite.swuB = f.fromBig(hexToBig(secp256k1IsoB))
+        suite.xNum = hexToElements(f, secp256k1IsoXNum)
+        suite.xDen = hexToElements(f, secp256k1IsoXDen)
+        suite.yNum = hexToElements(f, secp256k1IsoYNum)
+        suite.yDen = hexToElements(f, secp256k1IsoYDen)
+    } else {
+        suite.swuA = suite.arith.a
+        suite.swuB = f.fromBig(b)
+    }
+
+    // All the supported primes are 3 mod 4.
+    if p.Bit(0) != 1 || p.Bit(1) != 1 {
+        return nil, errors.New("HashToCurve needs a prime that is 3 mod 4")
+    }
+    suite.c1 = new(big.Int).Rsh(p, 2)
+    suite.c2 = f.newElement()
+    negZ := f.newElement()
+    f.neg(negZ, suite.z)
+    f.exp(suite.c2, negZ, new(big.Int).Add(suite.c1, big.NewInt(1)))
+    return suite, nil
+}
+
+func hexToBig(s string) *big.Int {
+    x, _ := new(big.Int).SetString(s, 16)
+    return x
+}
+
+func hexToElements(f *primeField, hexes []string) []fieldElement {
+    elements := make([]fieldElement, len(hexes))
+    for i, s := range hexes {
+        elements[i] = f.fromBig(hexToBig(s))
+    }
+    return elements
+}
+
+// Returns the uniform bytes of expand_message_xmd (RFC 9380, section 5.3.1).
+func expandMessageXMD(newHash func() hash.Hash, msg, dst []byte, lenInBytes int) ([]byte, error) {
+    h := newHash()
+    bInBytes := h.Size()
+    sInBytes := h.BlockSize()
+
+    if len(dst) > 255 {
+        h.Write([]byte("H2C-OVERSIZE-DST-"))
+        h.Write(dst)
+        dst = h.Sum(nil)
+        h.Reset()
+    }
+
+    ell := (lenInBytes + bInBytes - 1) / bInBytes
+    if ell > 255 || lenInBytes > 65535 {
+        return nil, errors.New("The requested length is too large for expand_message_xmd")
+    }
+    dstPrime := append(append([]byte{}, dst...), byte(len(dst)))
+
+    // b_0 = H(Z_pad || msg || l_i_b_str || I2OSP(0, 1) || DST_prime)
+    h.Write(make([]byte, sInBytes))
+    h.Write(msg)
+    h.Write([]byte{byte(lenInBytes >> 8), byte(lenInBytes), 0})
+    h.Write(dstPrime)
+    b0 := h.Sum(nil)
+
+    uniform := make([]byte, 0, ell * bInBytes)
+    bi := make([]byte, bInBytes)
+    for i := 1; i <= ell; i++ {
+        // b_i = H(strxor(b_0, b_(i - 1)) || I2OSP(i, 1) || DST_prime)
+        // where b_0 is used on its own for b_1.
+        for j := range bi {
+            bi[j] ^= b0[j]
+        }
+        h.Reset()
+        h.Write(bi)
+        h.Write([]byte{byte(i)})
+        h.Write(dstPrime)
+        bi = h.Sum(bi[:0])
+        uniform = append(uniform, bi...)
+    }
+    return uniform[:lenInBytes], nil
+}
+
+// Returns the big endian data reduced modulo p.
+func (m *hashToCurveSuite) hashToField(data []byte) fieldElement {
+    f := m.field
+    split := len(data) - 8 * f.n
+
+    // data = hi * 2^(64n) + lo, where lo < 2p and hi < p.
+    hi := f.fromBytes(data[:split])
+    lo := f.fromBytes(data[split:])
+
+    e := f.newElement()
+    f.mul(e, hi, m.wide)
+    f.add(e, e, lo)
+    return e
+}
+
+// Returns the affine coordinates of the Point of the curve that
+// the field element maps to.
+func (m *hashToCurveSuite) mapToCurve(u fieldElement) (fieldElement, fieldElement) {
+    x, y := m.mapToCurveSimpleSWU(u)
+    if m.xNum == nil {
+        return x, y
+    }
+    return m.isoMap(x, y)
+}
+
+// The straight-line simplified SWU map from RFC 9380, appendix F.2.
+func (m *hashToCurveSuite) mapToCurveSimpleSWU(u fieldElement) (fieldElement, fieldElement) {
+    f := m.field
+    tv1 := f.newElement()
+    tv2 := f.newElement()
+    tv3 := f.newElement()
+    tv4 := f.newElement()
+    tv5 := f.newElement()
+    tv6 := f.newElement()
+    x := f.newElement()
+    y := f.newElement()
+
+    f.square(tv1, u)
+    f.mul(tv1, m.z, tv1)
+    f.square(tv2, tv1)
+    f.add(tv2, tv2, tv1)
+    f.add(tv3, tv2, f.one)
+    f.mul(tv3, m.swuB, tv3)
+
+    // tv4 = CMOV(Z, -tv2, tv2 != 0)
+    f.neg(tv4, tv2)
+    f.cmov(tv4, tv4, m.z, f.isZero(tv2))
+    f.mul(tv4, m.swuA, tv4)
+
+    f.square(tv2, tv3)
+    f.square(tv6, tv4)
+    f.mul(tv5, m.swuA, tv6)
+    f.add(tv2, tv2, tv5)
+    f.mul(tv2, tv2, tv3)
+    f.mul(tv6, tv6, tv4)
+    f.mul(tv5, m.swuB, tv6)
+    f.add(tv2, tv2, tv5)
+    f.mul(x, tv1, tv3)
+
+    isGx1Square, y1 := m.sqrtRatio(tv2, tv6)
+
+    f.mul(y, tv1, u)
+    f.mul(y, y, y1)
+    f.cmov(x, x, tv3, isGx1Square)
+    f.cmov(y, y, y1, isGx1Square)
+
+    // y = CMOV(-y, y, sgn0(u) == sgn0(y))
+    negY := f.newElement()
+    f.neg(negY, y)
+    f.cmov(y, negY, y, 1 ^ f.sgn0(u) ^ f.sgn0(y))
+
+    f.inv(tv4, tv4)
+    f.mul(x, x, tv4)
+    return x, y
+}
+
+// The sqrt_ratio for p = 3 mod 4 from RFC 9380, appendix F.2.1.2.
+//
+// It returns 1 and sqrt(u / v) if u / v is square,
+// and 0 and sqrt(Z * u / v) otherwise.
+func (m *hashToCurveSuite) sqrtRatio(u, v fieldElement) (uint64, fieldElement) {
+    f := m.field
+    tv1 := f.newElement()
+    tv2 := f.newElement()
+    tv3 := f.newElement()
+    y1 := f.newElement()
+    y2 := f.newElement()
+
+    f.square(tv1, v)
+    f.mul(tv2, u, v)
+    f.mul(tv1, tv1, tv2)
+    f.exp(y1, tv1, m.c1)
+    f.mul(y1, y1, tv2)
+    f.mul(y2, y1, m.c2)
+    f.square(tv3, y1)
+    f.mul(tv3, tv3, v)
+
+    isQR := f.equal(tv3, u)
+    f.cmov(y1, y2, y1, isQR)
+    return isQR, y1
+}
+
+// Returns the image of the Point of the isogenous curve by the isogeny map.
+func (m *hashToCurveSuite) isoMap(x, y fieldElement) (fieldElement, fieldElement) {
+    f := m.field
+    xNum := m.evalPoly(m.xNum, x)
+    xDen := m.evalPoly(m.xDen, x)
+    yNum := m.evalPoly(m.yNum, x)
+    yDen := m.evalPoly(m.yDen, x)
+
+    f.inv(xDen, xDen)
+    f.inv(yDen, yDen)
+
+    isoX := f.newElement()
+    f.mul(isoX, xNum, xDen)
+
+    isoY := f.newElement()
+    f.mul(isoY, y, yNum)
+    f.mul(isoY, isoY, yDen)
+    return isoX, isoY
+}
+
+// Evaluates the polynomial with the given coefficients at x with Horner's method.
+func (m *hashToCurveSuite) evalPoly(coeffs []fieldElement, x fieldElement) fieldElement {
+    f := m.field
+    result := f.newElement()
+    copy(result, coeffs[len(coeffs) - 1])
+    for i := len(coeffs) - 2; i >= 0; i-- {
+        f.mul(result, result, x)
+        f.add(result, result, coeffs[i])
+    }
+    return result
+}
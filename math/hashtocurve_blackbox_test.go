@@ -0,0 +1,118 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package math_test
+
+import (
+    "testing"
+    "encoding/json"
+    "io/ioutil"
+    "math/big"
+    "github.com/nucypher/goUmbral/math"
+    "github.com/nucypher/goUmbral/openssl"
+)
+
+type HashToCurveVectors struct {
+    Ciphersuite string `json:"ciphersuite"`
+    DST string `json:"dst"`
+    Vectors []HashToCurveVector `json:"vectors"`
+}
+
+type HashToCurveVector struct {
+    Msg string `json:"msg"`
+    P AffinePoint `json:"P"`
+}
+
+type AffinePoint struct {
+    X string `json:"x"`
+    Y string `json:"y"`
+}
+
+func testHashToCurveVectors(t *testing.T, file string, curve *openssl.Curve) {
+    data, err := ioutil.ReadFile(file)
+    if err != nil {
+        t.Error(err)
+    }
+
+    var vectors HashToCurveVectors
+    err = json.Unmarshal(data, &vectors)
+    if err != nil {
+        t.Error(err)
+    }
+
+    for _, k := range vectors.Vectors {
+        point, err := math.HashToCurve([]byte(k.Msg), []byte(vectors.DST), curve)
+        if err != nil {
+            t.Error(err)
+            continue
+        }
+
+        x, y, err := point.ToAffine()
+        if err != nil {
+            t.Error(err)
+        }
+        point.Free()
+
+        expectedX, _ := new(big.Int).SetString(k.P.X, 0)
+        expectedY, _ := new(big.Int).SetString(k.P.Y, 0)
+        if x.Cmp(expectedX) != 0 || y.Cmp(expectedY) != 0 {
+            t.Errorf("%s: the hash of %q was not equal to the vector", vectors.Ciphersuite, k.Msg)
+        }
+    }
+}
+
+func TestHashToCurveVectors(t *testing.T) {
+    t.Run("P-256", func(t *testing.T) {
+        curve, err := openssl.NewCurve(openssl.SECP256R1)
+        if err != nil {
+            t.Error(err)
+        }
+        defer curve.Free()
+
+        testHashToCurveVectors(t, "../vectors/vectors_hash_to_curve_p256.json", curve)
+    })
+    t.Run("P-384", func(t *testing.T) {
+        curve, err := openssl.NewCurve(openssl.SECP384R1)
+        if err != nil {
+            t.Error(err)
+        }
+        defer curve.Free()
+
+        testHashToCurveVectors(t, "../vectors/vectors_hash_to_curve_p384.json", curve)
+    })
+    t.Run("secp256k1", func(t *testing.T) {
+        curve, err := openssl.NewCurve(openssl.SECP256K1)
+        if err != nil {
+            t.Error(err)
+        }
+        defer curve.Free()
+
+        testHashToCurveVectors(t, "../vectors/vectors_hash_to_curve_secp256k1.json", curve)
+    })
+}
+
+func TestHashToCurveEmptyDST(t *testing.T) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Error(err)
+    }
+    defer curve.Free()
+
+    _, err = math.HashToCurve([]byte("abc"), nil, curve)
+    if err == nil {
+        t.Error("A message was hashed without a domain separation tag")
+    }
+}
@@ -0,0 +1,198 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package math
+
+import (
+    "bytes"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "io/ioutil"
+    "math/big"
+    "testing"
+    "github.com/nucypher/goUmbral/openssl"
+)
+
+type expandVectors struct {
+    DST string `json:"DST"`
+    Tests []expandVector `json:"tests"`
+}
+
+type expandVector struct {
+    LenInBytes string `json:"len_in_bytes"`
+    Msg string `json:"msg"`
+    UniformBytes string `json:"uniform_bytes"`
+}
+
+func TestExpandMessageXMD(t *testing.T) {
+    files := []string{
+        "../vectors/vectors_expand_message_xmd_sha256_38.json",
+        "../vectors/vectors_expand_message_xmd_sha256_256.json",
+    }
+    for _, file := range files {
+        data, err := ioutil.ReadFile(file)
+        if err != nil {
+            t.Error(err)
+        }
+
+        var vectors expandVectors
+        err = json.Unmarshal(data, &vectors)
+        if err != nil {
+            t.Error(err)
+        }
+
+        for _, k := range vectors.Tests {
+            length, _ := new(big.Int).SetString(k.LenInBytes, 0)
+
+            uniform, err := expandMessageXMD(sha256.New, []byte(k.Msg),
+                []byte(vectors.DST), int(length.Int64()))
+            if err != nil {
+                t.Error(err)
+            }
+
+            expected, err := hex.DecodeString(k.UniformBytes)
+            if err != nil {
+                t.Error(err)
+            }
+
+            if !bytes.Equal(uniform, expected) {
+                t.Errorf("The expansion of %q was not equal to the vector", k.Msg)
+            }
+        }
+    }
+}
+
+// Checks the intermediate values u, Q0 and Q1 of the hash_to_curve vectors.
+func TestHashToCurveMaps(t *testing.T) {
+    p256, err := openssl.NewCurve(openssl.SECP256R1)
+    if err != nil {
+        t.Error(err)
+    }
+    defer p256.Free()
+
+    p384, err := openssl.NewCurve(openssl.SECP384R1)
+    if err != nil {
+        t.Error(err)
+    }
+    defer p384.Free()
+
+    secp256k1, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Error(err)
+    }
+    defer secp256k1.Free()
+
+    files := map[*openssl.Curve]string{
+        p256: "../vectors/vectors_hash_to_curve_p256.json",
+        p384: "../vectors/vectors_hash_to_curve_p384.json",
+        secp256k1: "../vectors/vectors_hash_to_curve_secp256k1.json",
+    }
+
+    for curve, file := range files {
+        data, err := ioutil.ReadFile(file)
+        if err != nil {
+            t.Error(err)
+        }
+
+        var vectors struct {
+            DST string `json:"dst"`
+            Vectors []struct {
+                Msg string `json:"msg"`
+                Q0 map[string]string `json:"Q0"`
+                Q1 map[string]string `json:"Q1"`
+                U []string `json:"u"`
+            } `json:"vectors"`
+        }
+        err = json.Unmarshal(data, &vectors)
+        if err != nil {
+            t.Error(err)
+        }
+
+        suite, err := getHashToCurveSuite(curve)
+        if err != nil {
+            t.Error(err)
+        }
+        f := suite.field
+
+        for _, k := range vectors.Vectors {
+            uniform, err := expandMessageXMD(suite.newHash, []byte(k.Msg),
+                []byte(vectors.DST), 2 * suite.l)
+            if err != nil {
+                t.Error(err)
+            }
+
+            for i, q := range []map[string]string{k.Q0, k.Q1} {
+                u := suite.hashToField(uniform[i * suite.l:(i + 1) * suite.l])
+                if f.toBig(u).Cmp(hexToBig(k.U[i][2:])) != 0 {
+                    t.Errorf("%s: u%d of %q was not equal to the vector", file, i, k.Msg)
+                }
+
+                x, y := suite.mapToCurve(u)
+                if f.toBig(x).Cmp(hexToBig(q["x"][2:])) != 0 ||
+                        f.toBig(y).Cmp(hexToBig(q["y"][2:])) != 0 {
+                    t.Errorf("%s: Q%d of %q was not equal to the vector", file, i, k.Msg)
+                }
+            }
+        }
+    }
+}
+
+func TestPrimeFieldArithmetic(t *testing.T) {
+    p := hexToBig("fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffeffffffff0000000000000000ffffffff")
+    f := newPrimeField(p)
+
+    for i := 0; i < 100; i++ {
+        a, _ := rand.Int(rand.Reader, p)
+        b, _ := rand.Int(rand.Reader, p)
+        x := f.fromBig(a)
+        y := f.fromBig(b)
+        z := f.newElement()
+
+        f.add(z, x, y)
+        expected := new(big.Int).Add(a, b)
+        if f.toBig(z).Cmp(expected.Mod(expected, p)) != 0 {
+            t.Error("The sum was not correct")
+        }
+
+        f.sub(z, x, y)
+        expected = new(big.Int).Sub(a, b)
+        if f.toBig(z).Cmp(expected.Mod(expected, p)) != 0 {
+            t.Error("The difference was not correct")
+        }
+
+        f.mul(z, x, y)
+        expected = new(big.Int).Mul(a, b)
+        if f.toBig(z).Cmp(expected.Mod(expected, p)) != 0 {
+            t.Error("The product was not correct")
+        }
+
+        f.inv(z, x)
+        expected = new(big.Int).ModInverse(a, p)
+        if f.toBig(z).Cmp(expected) != 0 {
+            t.Error("The inverse was not correct")
+        }
+
+        if f.equal(x, y) != 0 || f.equal(x, x) != 1 {
+            t.Error("The equality was not correct")
+        }
+    }
+
+    if f.isZero(f.newElement()) != 1 || f.isZero(f.one) != 0 {
+        t.Error("The zero check was not correct")
+    }
+}
@@ -0,0 +1,187 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package math_test
+
+import (
+    "runtime"
+    "testing"
+    "time"
+    "github.com/nucypher/goUmbral/math"
+    "github.com/nucypher/goUmbral/openssl"
+)
+
+// Returns the number of live BIGNUMs and EC_POINTs,
+// after letting the finalizers of unreachable objects run.
+func liveObjects() (int64, int64) {
+    for i := 0; i < 10; i++ {
+        runtime.GC()
+        time.Sleep(time.Millisecond)
+    }
+    return openssl.LiveBigNums(), openssl.LiveECPoints()
+}
+
+func checkNoLeaks(t *testing.T, f func()) {
+    if !openssl.LeakCheck {
+        t.Skip("Build with -tags leakcheck to count the allocations")
+    }
+    bns, points := liveObjects()
+    f()
+    afterBNs, afterPoints := liveObjects()
+    if afterBNs != bns || afterPoints != points {
+        t.Error("Leaked", afterBNs - bns, "BIGNUMs and", afterPoints - points, "EC_POINTs")
+    }
+}
+
+func TestErrorPathsDoNotLeak(t *testing.T) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer curve.Free()
+
+    checkNoLeaks(t, func() {
+        // x = 5 is not the x coordinate of a point of secp256k1.
+        data := make([]byte, 33)
+        data[0], data[32] = 2, 5
+        _, err := math.BytesToPoint(data, curve)
+        if err == nil {
+            t.Error("A point off the curve was decoded")
+        }
+
+        // p + 1 is not a canonical x coordinate.
+        for i := 1; i < 33; i++ {
+            data[i] = 0xff
+        }
+        _, err = math.BytesToPoint(data, curve)
+        if err == nil {
+            t.Error("A non-canonical point was decoded")
+        }
+
+        data[0] = 4
+        _, err = math.BytesToPoint(append(data, data[1:]...), curve)
+        if err == nil {
+            t.Error("An uncompressed point off the curve was decoded")
+        }
+
+        _, err = math.BytesToModBN(data[1:], curve)
+        if err == nil {
+            t.Error("A bignum above the order was decoded")
+        }
+
+        _, err = math.IntToModBN(0, curve)
+        if err == nil {
+            t.Error("Zero was converted to a ModBigNum")
+        }
+    })
+}
+
+func TestFreeIsIdempotent(t *testing.T) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer curve.Free()
+
+    checkNoLeaks(t, func() {
+        point, err := math.GenRandPoint(curve)
+        if err != nil {
+            t.Fatal(err)
+        }
+        point.Free()
+        point.Free()
+
+        bn, err := math.GenRandModBN(curve)
+        if err != nil {
+            t.Fatal(err)
+        }
+        bn.Free()
+        bn.Free()
+
+        var nilPoint *math.Point
+        nilPoint.Free()
+
+        var nilBN *math.ModBigNum
+        nilBN.Free()
+
+        // The generator belongs to the curve.
+        math.GetGeneratorFromCurve(curve).Free()
+    })
+
+    other, err := openssl.NewCurve(openssl.SECP256R1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    other.Free()
+    other.Free()
+}
+
+func TestFreeFields(t *testing.T) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer curve.Free()
+
+    ecPoint, err := openssl.NewECPoint(curve)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // Neither field is at the start of the allocation of the struct.
+    holder := &struct {
+        Tag string
+        K math.ModBigNum
+        P math.Point
+    }{
+        Tag: "key",
+        K: math.ModBigNum{Bignum: openssl.NewBigNum(), Curve: curve},
+        P: math.Point{ECPoint: ecPoint, Curve: curve},
+    }
+    holder.K.Free()
+    holder.P.Free()
+
+    if holder.K.Bignum != nil || holder.P.ECPoint != nil {
+        t.Error("The fields were not freed")
+    }
+}
+
+func TestFinalizersFree(t *testing.T) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer curve.Free()
+
+    checkNoLeaks(t, func() {
+        for i := 0; i < 100; i++ {
+            point, err := math.GenRandPoint(curve)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            bn, err := math.GenRandModBN(curve)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            err = point.Mul(point, bn)
+            if err != nil {
+                t.Fatal(err)
+            }
+        }
+    })
+}
@@ -17,8 +17,12 @@
 package math
 
 import (
+    "crypto/subtle"
     "errors"
-    "golang.org/x/crypto/blake2b"
+    "fmt"
+    "math/big"
+    "runtime"
+    "encoding/binary"
     "github.com/nucypher/goUmbral/openssl"
 )
 
@@ -27,6 +31,14 @@ import (
 type ModBigNum struct {
    Bignum openssl.BigNum
    Curve *openssl.Curve
+
+   // The reference to Curve held until Free, if the ModBigNum was built
+   // by this package.
+   curveRef *openssl.Curve
+
+   // Whether a finalizer is set on the ModBigNum, which is only the case
+   // for the ones allocated by this package.
+   finalized bool
 }
 
 func NewModBigNum(cNum openssl.BigNum, curve *openssl.Curve) (*ModBigNum, error) {
@@ -34,14 +46,63 @@ func NewModBigNum(cNum openssl.BigNum, curve *openssl.Curve) (*ModBigNum, error)
         // Return the ModBigNum only if the provided Bignum is within
         // the order of the curve.
         if !openssl.BNIsWithinOrder(cNum, curve) {
-            return nil, errors.New("The provided BIGNUM is not on the provided curve.")
+            return nil, ErrScalarOutOfRange
         }
     }
-    return &ModBigNum{Bignum: cNum, Curve: curve}, nil
+    return newModBigNum(cNum, curve), nil
 }
 
-func ExpectedBytesLength(curve *openssl.Curve) {
-    // TODO: Return the size of a modbn given the curve.
+// Returns a ModBigNum holding zero, to hold the result of an operation.
+func NewZeroModBN(curve *openssl.Curve) *ModBigNum {
+    return newModBigNum(openssl.NewBigNum(), curve)
+}
+
+// Returns the ModBigNum x modulo the order of the curve.
+//
+// x may be negative or larger than the order.
+func BigIntToModBN(x *big.Int, curve *openssl.Curve) (*ModBigNum, error) {
+    order, err := openssl.BNToBigInt(curve.Order)
+    if err != nil {
+        return nil, err
+    }
+    reduced := new(big.Int).Mod(x, order)
+
+    bn, err := openssl.BigIntToBN(reduced)
+    if err != nil {
+        return nil, err
+    }
+    return newModBigNum(bn, curve), nil
+}
+
+// The length (in bytes) of the input of WideBytesToModBN.
+const WideBytesLength = 64
+
+// Returns the ModBigNum of the 64 big endian bytes modulo the order
+// of the curve, such as the output of a 512 bit hash function.
+//
+// The bytes are at least 128 bits longer than the order of the supported
+// curves, so that the result is uniform up to a negligible bias if
+// the bytes are uniform. The result may be zero.
+func WideBytesToModBN(data []byte, curve *openssl.Curve) (*ModBigNum, error) {
+    if len(data) != WideBytesLength {
+        return nil, &EncodingError{Reason: "The wide bytes must be 64 bytes long"}
+    }
+    if ExpectedBytesLength(curve) + 16 > WideBytesLength {
+        return nil, ErrUnsupportedCurve
+    }
+
+    m := NewZeroModBN(curve)
+    err := m.setReduced(data)
+    if err != nil {
+        m.Free()
+        return nil, err
+    }
+    return m, nil
+}
+
+// Returns the size (in bytes) of a serialized ModBigNum given a curve.
+func ExpectedBytesLength(curve *openssl.Curve) uint {
+    return uint(openssl.SizeOfBN(curve.Order))
 }
 
 // Returns a ModBigNum with a cryptographically secure OpenSSL BIGNUM
@@ -54,6 +115,7 @@ func GenRandModBN(curve *openssl.Curve) (*ModBigNum, error) {
     newRandBN := openssl.NewBigNum()
     err := openssl.RandRangeBN(newRandBN, curve.Order)
     if err != nil {
+        openssl.FreeBigNum(newRandBN)
         return nil, err
     }
 
@@ -61,7 +123,7 @@ func GenRandModBN(curve *openssl.Curve) (*ModBigNum, error) {
         openssl.FreeBigNum(newRandBN)
         return GenRandModBN(curve)
     }
-    return &ModBigNum{Bignum: newRandBN, Curve: curve}, nil
+    return newModBigNum(newRandBN, curve), nil
 }
 
 func IntToModBN(num int, curve *openssl.Curve) (*ModBigNum, error) {
@@ -70,19 +132,76 @@ func IntToModBN(num int, curve *openssl.Curve) (*ModBigNum, error) {
         return nil, err
     }
     if !openssl.BNIsWithinOrder(newBN, curve) {
-        return nil, errors.New("Bignum is not within the curve")
+        openssl.FreeBigNum(newBN)
+        return nil, ErrScalarOutOfRange
     }
 
-    return &ModBigNum{Bignum: newBN, Curve: curve}, nil
+    return newModBigNum(newBN, curve), nil
 }
 
-// Returns a ModBigNum based on provided data hashed by blake2b.
+// Returns a ModBigNum based on provided data hashed with the hash function
+// of the parameters.
 func HashToModBN(bytes []byte, params *UmbralParameters) (*ModBigNum, error) {
-    hash := blake2b.Sum512(bytes)
-    hashBN, err := openssl.BytesToBN(hash[:])
+    return HashItemsToModBN(params, nil, bytes)
+}
+
+// Returns a ModBigNum from the hash of the items with the hash function
+// of the parameters, in the same way as pyUmbral's CurveBN.hash().
+//
+// Each item must be a *Point, which is hashed in compressed form,
+// a *ModBigNum or a []byte. The serializations of the items are hashed
+// in order, and the digest is reduced to [1, n - 1]. Hash functions with
+// digests shorter than the order plus 16 bytes are expanded to that length
+// to keep the bias of the reduction negligible.
+//
+// If customization is not empty, it is hashed first, prefixed with
+// its length as a 4 byte big endian integer, so that hashes computed
+// for different purposes are independent.
+func HashItemsToModBN(params *UmbralParameters, customization []byte,
+        items ...interface{}) (*ModBigNum, error) {
+    var input []byte
+
+    if len(customization) > 0 {
+        lenCustomization := make([]byte, 4)
+        binary.BigEndian.PutUint32(lenCustomization, uint32(len(customization)))
+        input = append(input, lenCustomization...)
+        input = append(input, customization...)
+    }
+
+    for _, item := range items {
+        var data []byte
+        var err error
+        switch item := item.(type) {
+        case *Point:
+            data, err = item.ToBytes(true)
+        case *ModBigNum:
+            data, err = item.Bytes()
+        case []byte:
+            data = item
+        default:
+            err = fmt.Errorf("Cannot hash an item of type %T", item)
+        }
+        if err != nil {
+            return nil, err
+        }
+        input = append(input, data...)
+    }
+
+    length := int(ExpectedBytesLength(params.Curve)) + 16
+    digest, err := params.Hash.expand(input, length)
     if err != nil {
         return nil, err
     }
+    return digestToModBN(digest, params.Curve)
+}
+
+// Returns the digest reduced to a ModBigNum in [1, n - 1].
+func digestToModBN(digest []byte, curve *openssl.Curve) (*ModBigNum, error) {
+    hashBN, err := openssl.BytesToBN(digest)
+    if err != nil {
+        return nil, err
+    }
+    defer openssl.FreeBigNum(hashBN)
 
     oneBN, err := openssl.IntToBN(1)
     if err != nil {
@@ -90,34 +209,42 @@ func HashToModBN(bytes []byte, params *UmbralParameters) (*ModBigNum, error) {
     }
     defer openssl.FreeBigNum(oneBN)
 
-    ctx := openssl.NewBNCtx()
-    defer openssl.FreeBNCtx(ctx)
+    ctx := openssl.GetBNCtx()
+    defer openssl.PutBNCtx(ctx)
 
     result := openssl.NewBigNum()
 
-    err = openssl.SubBN(result, params.Curve.Order, oneBN)
+    err = openssl.SubBN(result, curve.Order, oneBN)
     if err != nil {
+        openssl.FreeBigNum(result)
         return nil, err
     }
 
     err = openssl.ModBN(result, hashBN, result, ctx)
     if err != nil {
+        openssl.FreeBigNum(result)
         return nil, err
     }
 
     err = openssl.AddBN(result, result, oneBN)
     if err != nil {
+        openssl.FreeBigNum(result)
         return nil, err
     }
 
-    return &ModBigNum{Bignum: result, Curve: params.Curve}, nil
+    return newModBigNum(result, curve), nil
 }
 
 // Returns the ModBigNum associated with the bytes-converted bignum
 // provided by the data argument.
+//
+// The data must be exactly ExpectedBytesLength bytes long.
 func BytesToModBN(data []byte, curve *openssl.Curve) (*ModBigNum, error) {
     if len(data) == 0 {
-        return nil, errors.New("No bytes failure")
+        return nil, &EncodingError{Reason: "No bytes failure"}
+    }
+    if uint(len(data)) != ExpectedBytesLength(curve) {
+        return nil, &EncodingError{Reason: "The bignum does not have the right size for the curve"}
     }
 
     bignum, err := openssl.BytesToBN(data)
@@ -125,21 +252,30 @@ func BytesToModBN(data []byte, curve *openssl.Curve) (*ModBigNum, error) {
         return nil, err
     }
     if !openssl.BNIsWithinOrder(bignum, curve) {
-        return nil, errors.New("Bignum is not within the curve")
+        openssl.FreeBigNum(bignum)
+        return nil, ErrScalarOutOfRange
     }
 
-    return &ModBigNum{Bignum: bignum, Curve: curve}, nil
+    return newModBigNum(bignum, curve), nil
 }
 
+// Returns the ModBigNum serialized as bytes,
+// left padded with zeros to ExpectedBytesLength.
 func (m *ModBigNum) Bytes() ([]byte, error) {
-    return openssl.BNToBytes(m.Bignum)
+    defer keepAlive(m)
+
+    return openssl.BNToPaddedBytes(m.Bignum, int(ExpectedBytesLength(m.Curve)))
 }
 
 func (m *ModBigNum) Equals(other *ModBigNum) bool {
+    defer keepAlive(m, other)
+
     return openssl.CmpBN(m.Bignum, other.Bignum) == 0
 }
 
-func (m ModBigNum) Compare(other *ModBigNum) int {
+func (m *ModBigNum) Compare(other *ModBigNum) int {
+    defer keepAlive(m, other)
+
     // -1 less than, 0 is equal to, 1 is greater than
     return openssl.CmpBN(m.Bignum, other.Bignum)
 }
@@ -151,13 +287,16 @@ func (m ModBigNum) Compare(other *ModBigNum) int {
 //
 // Pow will return the error if one occurred, and nil otherwise.
 func (z *ModBigNum) Pow(x, y *ModBigNum) error {
+    defer keepAlive(z, x, y)
+
     if !x.Curve.Equals(y.Curve) || !x.Curve.Equals(z.Curve) {
-        return errors.New("ModBigNum Pow Error: The curves are not equal")
+        return fmt.Errorf("ModBigNum Pow Error: %w", ErrCurveMismatch)
     }
-    ctx := openssl.NewBNCtx()
-    defer openssl.FreeBNCtx(ctx)
+    ctx := openssl.GetBNCtx()
+    defer openssl.PutBNCtx(ctx)
 
-    err := openssl.ModExpMontBN(z.Bignum, x.Bignum, y.Bignum, x.Curve.Order, ctx)
+    err := openssl.ModExpMontBN(z.Bignum, x.Bignum, y.Bignum, x.Curve.Order, ctx,
+        x.Curve.OrderMontCtx())
     if err != nil {
         return err
     }
@@ -171,11 +310,13 @@ func (z *ModBigNum) Pow(x, y *ModBigNum) error {
 //
 // Mul will return the error if one occurred, and nil otherwise.
 func (z *ModBigNum) Mul(x, y *ModBigNum) error {
+    defer keepAlive(z, x, y)
+
     if !x.Curve.Equals(y.Curve) || !x.Curve.Equals(z.Curve) {
-        return errors.New("ModBigNum Mul Error: The curves are not equal")
+        return fmt.Errorf("ModBigNum Mul Error: %w", ErrCurveMismatch)
     }
-    ctx := openssl.NewBNCtx()
-    defer openssl.FreeBNCtx(ctx)
+    ctx := openssl.GetBNCtx()
+    defer openssl.PutBNCtx(ctx)
 
     err := openssl.ModMulBN(z.Bignum, x.Bignum, y.Bignum, x.Curve.Order, ctx)
     if err != nil {
@@ -217,11 +358,13 @@ func (z *ModBigNum) Div(x, y *ModBigNum) error {
 //
 // Add will return the error if one occurred, and nil otherwise.
 func (z *ModBigNum) Add(x, y *ModBigNum) error {
+    defer keepAlive(z, x, y)
+
     if !x.Curve.Equals(y.Curve) || !x.Curve.Equals(z.Curve) {
-        return errors.New("ModBigNum Add Error: The curves are not equal")
+        return fmt.Errorf("ModBigNum Add Error: %w", ErrCurveMismatch)
     }
-    ctx := openssl.NewBNCtx()
-    defer openssl.FreeBNCtx(ctx)
+    ctx := openssl.GetBNCtx()
+    defer openssl.PutBNCtx(ctx)
 
     err := openssl.ModAddBN(z.Bignum, x.Bignum, y.Bignum, x.Curve.Order, ctx)
     if err != nil {
@@ -237,11 +380,13 @@ func (z *ModBigNum) Add(x, y *ModBigNum) error {
 //
 // Sub will return the error if one occurred, and nil otherwise.
 func (z *ModBigNum) Sub(x, y *ModBigNum) error {
+    defer keepAlive(z, x, y)
+
     if !x.Curve.Equals(y.Curve) || !x.Curve.Equals(z.Curve) {
-        return errors.New("ModBigNum Sub Error: The curves are not equal")
+        return fmt.Errorf("ModBigNum Sub Error: %w", ErrCurveMismatch)
     }
-    ctx := openssl.NewBNCtx()
-    defer openssl.FreeBNCtx(ctx)
+    ctx := openssl.GetBNCtx()
+    defer openssl.PutBNCtx(ctx)
 
     err := openssl.ModSubBN(z.Bignum, x.Bignum, y.Bignum, x.Curve.Order, ctx)
     if err != nil {
@@ -257,11 +402,13 @@ func (z *ModBigNum) Sub(x, y *ModBigNum) error {
 //
 // Invert will return the error if one occurred, and nil otherwise.
 func (z *ModBigNum) Invert(x *ModBigNum) error {
+    defer keepAlive(z, x)
+
     if !x.Curve.Equals(z.Curve) {
-        return errors.New("ModBigNum Invert Error: The curves are not equal")
+        return fmt.Errorf("ModBigNum Invert Error: %w", ErrCurveMismatch)
     }
-    ctx := openssl.NewBNCtx()
-    defer openssl.FreeBNCtx(ctx)
+    ctx := openssl.GetBNCtx()
+    defer openssl.PutBNCtx(ctx)
 
     err := openssl.ModInvertBN(z.Bignum, x.Bignum, x.Curve.Order, ctx)
     if err != nil {
@@ -277,11 +424,13 @@ func (z *ModBigNum) Invert(x *ModBigNum) error {
 //
 // Neg will return the error if one occurred, and nil otherwise.
 func (z *ModBigNum) Neg(x *ModBigNum) error {
+    defer keepAlive(z, x)
+
     if !x.Curve.Equals(z.Curve) {
-        return errors.New("ModBigNum Neg Error: The curves are not equal")
+        return fmt.Errorf("ModBigNum Neg Error: %w", ErrCurveMismatch)
     }
-    ctx := openssl.NewBNCtx()
-    defer openssl.FreeBNCtx(ctx)
+    ctx := openssl.GetBNCtx()
+    defer openssl.PutBNCtx(ctx)
 
     err := openssl.ModNegBN(z.Bignum, x.Bignum, x.Curve.Order, ctx)
     if err != nil {
@@ -297,30 +446,234 @@ func (z *ModBigNum) Neg(x *ModBigNum) error {
 //
 // Mod will return the error, and nil otherwise.
 func (z *ModBigNum) Mod(x, y *ModBigNum) error {
+    defer keepAlive(z, x, y)
+
     if !x.Curve.Equals(y.Curve) || !x.Curve.Equals(z.Curve) {
-        return errors.New("ModBigNum Mod Error: The curves are not equal")
+        return fmt.Errorf("ModBigNum Mod Error: %w", ErrCurveMismatch)
+    }
+    ctx := openssl.GetBNCtx()
+    defer openssl.PutBNCtx(ctx)
+
+    err := openssl.ModModBN(z.Bignum, x.Bignum, y.Bignum, ctx)
+    if err != nil {
+        return err
+    }
+    return nil
+}
+
+// Returns true if the ModBigNum is zero.
+func (m *ModBigNum) IsZero() bool {
+    defer keepAlive(m)
+
+    return openssl.IsZeroBN(m.Bignum)
+}
+
+// Returns true if the ModBigNum is one.
+func (m *ModBigNum) IsOne() bool {
+    defer keepAlive(m)
+
+    return openssl.IsOneBN(m.Bignum)
+}
+
+// Returns the ModBigNum as a big.Int.
+//
+// The big.Int is not cleared by the garbage collector,
+// so this should be avoided with secret values.
+func (m *ModBigNum) BigInt() (*big.Int, error) {
+    defer keepAlive(m)
+
+    return openssl.BNToBigInt(m.Bignum)
+}
+
+// ModBigNum.SetUint64() will set z to x modulo the order of its curve.
+//
+// SetUint64 will return the error if one occurred, and nil otherwise.
+func (z *ModBigNum) SetUint64(x uint64) error {
+    defer keepAlive(z)
+
+    data := make([]byte, 8)
+    binary.BigEndian.PutUint64(data, x)
+    return z.setReduced(data)
+}
+
+// Sets z to the big endian integer in data modulo the order of its curve.
+func (z *ModBigNum) setReduced(data []byte) error {
+    bn, err := openssl.BytesToBN(data)
+    if err != nil {
+        return err
+    }
+    defer openssl.FreeBigNum(bn)
+
+    ctx := openssl.GetBNCtx()
+    defer openssl.PutBNCtx(ctx)
+
+    return openssl.ModModBN(z.Bignum, bn, z.Curve.Order, ctx)
+}
+
+// ModBigNum.PowUint64() will perform (x^e) modulo the order of the curve of x.
+// It will then set z to the result of that operation.
+//
+// x and z must use the same curve and must be initialized.
+//
+// PowUint64 will return the error if one occurred, and nil otherwise.
+func (z *ModBigNum) PowUint64(x *ModBigNum, e uint64) error {
+    defer keepAlive(z, x)
+
+    if !x.Curve.Equals(z.Curve) {
+        return fmt.Errorf("ModBigNum PowUint64 Error: %w", ErrCurveMismatch)
     }
-    ctx := openssl.NewBNCtx()
-    defer openssl.FreeBNCtx(ctx)
+    data := make([]byte, 8)
+    binary.BigEndian.PutUint64(data, e)
 
-    err := openssl.ModNegBN(z.Bignum, x.Bignum, y.Bignum, ctx)
+    exp, err := openssl.BytesToBN(data)
+    if err != nil {
+        return err
+    }
+    defer openssl.FreeBigNum(exp)
+
+    ctx := openssl.GetBNCtx()
+    defer openssl.PutBNCtx(ctx)
+
+    return openssl.ModExpMontBN(z.Bignum, x.Bignum, exp, x.Curve.Order, ctx,
+        x.Curve.OrderMontCtx())
+}
+
+// ModBigNum.Sqrt() computes a square root of x modulo the order of the curve of x.
+// It will then set z to the result of that operation.
+//
+// x and z must use the same curve and must be initialized.
+// Sqrt does not run in constant time.
+//
+// Sqrt will return ErrNotASquare if x has no square root,
+// another error if one occurred, and nil otherwise.
+func (z *ModBigNum) Sqrt(x *ModBigNum) error {
+    defer keepAlive(z, x)
+
+    if !x.Curve.Equals(z.Curve) {
+        return fmt.Errorf("ModBigNum Sqrt Error: %w", ErrCurveMismatch)
+    }
+    ctx := openssl.GetBNCtx()
+    defer openssl.PutBNCtx(ctx)
+
+    err := openssl.ModSqrtBN(z.Bignum, x.Bignum, x.Curve.Order, ctx)
     if err != nil {
         return err
     }
     return nil
 }
 
+// ModBigNum.Legendre() returns the Legendre symbol of the ModBigNum
+// modulo the order of its curve: 1 if it is a non zero square, -1 if it
+// is not a square, and 0 if it is zero.
+//
+// It is computed as m^((n - 1) / 2) in constant time, and the result is
+// mapped to the symbol without branching on it.
+func (m *ModBigNum) Legendre() (int, error) {
+    defer keepAlive(m)
+
+    order := m.Curve.Order
+
+    one, err := openssl.IntToBN(1)
+    if err != nil {
+        return 0, err
+    }
+    defer openssl.FreeBigNum(one)
+
+    two, err := openssl.IntToBN(2)
+    if err != nil {
+        return 0, err
+    }
+    defer openssl.FreeBigNum(two)
+
+    ctx := openssl.GetBNCtx()
+    defer openssl.PutBNCtx(ctx)
+
+    minusOne := openssl.NewBigNum()
+    defer openssl.FreeBigNum(minusOne)
+
+    err = openssl.SubBN(minusOne, order, one)
+    if err != nil {
+        return 0, err
+    }
+
+    // (n - 1) / 2
+    exp := openssl.NewBigNum()
+    defer openssl.FreeBigNum(exp)
+
+    err = openssl.DivBN(exp, nil, minusOne, two, ctx)
+    if err != nil {
+        return 0, err
+    }
+
+    symbol := openssl.NewBigNum()
+    defer openssl.FreeBigNum(symbol)
+
+    err = openssl.ModExpMontBN(symbol, m.Bignum, exp, order, ctx, m.Curve.OrderMontCtx())
+    if err != nil {
+        return 0, err
+    }
+
+    // The symbol is 1, 0 or n - 1. Compare its padded bytes with those
+    // of 1 and n - 1 instead of branching on it.
+    size := int(ExpectedBytesLength(m.Curve))
+    symbolBytes, err := openssl.BNToPaddedBytes(symbol, size)
+    if err != nil {
+        return 0, err
+    }
+
+    minusOneBytes, err := openssl.BNToPaddedBytes(minusOne, size)
+    if err != nil {
+        return 0, err
+    }
+
+    oneBytes := make([]byte, size)
+    oneBytes[size - 1] = 1
+
+    isOne := subtle.ConstantTimeCompare(symbolBytes, oneBytes)
+    isMinusOne := subtle.ConstantTimeCompare(symbolBytes, minusOneBytes)
+    return isOne - isMinusOne, nil
+}
+
 func (m *ModBigNum) Copy() (*ModBigNum, error) {
+    defer keepAlive(m)
+
     // Deep copy of a ModBigNum EXCLUDING the curve.
     bn, err := openssl.DupBN(m.Bignum)
     if err != nil {
         return nil, err
     }
-    return &ModBigNum{Bignum: bn, Curve: m.Curve}, nil
+    return newModBigNum(bn, m.Curve), nil
 }
 
+// ModBigNum.Free() clears and frees the BIGNUM of the ModBigNum.
+//
+// It is safe to call Free more than once, and on a nil ModBigNum.
+// A ModBigNum returned by this package that is not freed is freed when
+// it is garbage collected.
 func (m *ModBigNum) Free() {
-    if m != nil {
+    if m == nil {
+        return
+    }
+    if m.Bignum != nil {
         openssl.FreeBigNum(m.Bignum)
+        m.Bignum = nil
     }
+    if m.curveRef != nil {
+        m.curveRef.Release()
+        m.curveRef = nil
+    }
+    // m may be a field of another struct, on which a finalizer cannot be set.
+    if m.finalized {
+        runtime.SetFinalizer(m, nil)
+        m.finalized = false
+    }
+}
+
+// Returns a ModBigNum which holds a reference to the curve,
+// and frees the BIGNUM when it is garbage collected.
+func newModBigNum(bignum openssl.BigNum, curve *openssl.Curve) *ModBigNum {
+    m := &ModBigNum{Bignum: bignum, Curve: curve, curveRef: curve.Retain(),
+        finalized: true}
+    runtime.SetFinalizer(m, (*ModBigNum).Free)
+    return m
 }
@@ -17,7 +17,9 @@
 package math_test
 
 import (
+    "errors"
     "testing"
+    "math/big"
     "encoding/json"
     "encoding/hex"
     "io/ioutil"
@@ -304,3 +306,319 @@ func TestHash2ModBN(t *testing.T) {
         }
     }
 }
+
+func TestHashItemsToModBNVectors(t *testing.T) {
+    data, err := ioutil.ReadFile("../vectors/vectors_curvebn_hash.json")
+    if err != nil {
+        t.Error(err)
+    }
+
+    var pops HashOps
+    err = json.Unmarshal(data, &pops)
+    if err != nil {
+        t.Error(err)
+    }
+
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Error(err)
+    }
+    defer curve.Free()
+
+    params, err := math.NewUmbralParameters(curve)
+    if err != nil {
+        t.Error(err)
+    }
+
+    for _, k := range pops.Vectors {
+        var items []interface{}
+        for _, m := range k.Input {
+            bytes, err := hex.DecodeString(m.Bytes)
+            if err != nil {
+                t.Error(err)
+            }
+
+            switch m.Class {
+            case "Point":
+                point, err := math.BytesToPoint(bytes, curve)
+                if err != nil {
+                    t.Error(err)
+                }
+                defer point.Free()
+                items = append(items, point)
+            case "CurveBN":
+                modbn, err := math.BytesToModBN(bytes, curve)
+                if err != nil {
+                    t.Error(err)
+                }
+                defer modbn.Free()
+                items = append(items, modbn)
+            default:
+                items = append(items, bytes)
+            }
+        }
+
+        modbn1, err := math.HashItemsToModBN(params, nil, items...)
+        if err != nil {
+            t.Error(err)
+        }
+        defer modbn1.Free()
+
+        tmp1, err := hex.DecodeString(k.Output)
+        if err != nil {
+            t.Error(err)
+        }
+
+        modbn2, err := math.BytesToModBN(tmp1, curve)
+        if err != nil {
+            t.Error(err)
+        }
+        defer modbn2.Free()
+
+        if !modbn1.Equals(modbn2) {
+            t.Error("After hashing:", k, ", the modbns were not equal")
+        }
+
+        // A customization string must give an independent hash.
+        modbn3, err := math.HashItemsToModBN(params, []byte("customized"), items...)
+        if err != nil {
+            t.Error(err)
+        }
+        defer modbn3.Free()
+
+        if modbn3.Equals(modbn2) {
+            t.Error("The customization string did not change the hash")
+        }
+    }
+
+    _, err = math.HashItemsToModBN(params, nil, 42)
+    if err == nil {
+        t.Error("An item of an unsupported type was hashed")
+    }
+}
+
+func benchmarkModBNOp(b *testing.B, op func(z, x, y *math.ModBigNum) error) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        b.Fatal(err)
+    }
+    defer curve.Free()
+
+    x, err := math.GenRandModBN(curve)
+    if err != nil {
+        b.Fatal(err)
+    }
+    defer x.Free()
+
+    y, err := math.GenRandModBN(curve)
+    if err != nil {
+        b.Fatal(err)
+    }
+    defer y.Free()
+
+    z, err := x.Copy()
+    if err != nil {
+        b.Fatal(err)
+    }
+    defer z.Free()
+
+    b.ReportAllocs()
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        err = op(z, x, y)
+        if err != nil {
+            b.Fatal(err)
+        }
+    }
+}
+
+func BenchmarkModBigNumAdd(b *testing.B) {
+    benchmarkModBNOp(b, (*math.ModBigNum).Add)
+}
+
+func BenchmarkModBigNumMul(b *testing.B) {
+    benchmarkModBNOp(b, (*math.ModBigNum).Mul)
+}
+
+func BenchmarkModBigNumPow(b *testing.B) {
+    benchmarkModBNOp(b, (*math.ModBigNum).Pow)
+}
+
+func BenchmarkModBigNumNeg(b *testing.B) {
+    benchmarkModBNOp(b, func(z, x, y *math.ModBigNum) error {
+        return z.Neg(x)
+    })
+}
+
+func TestModBigNumScalarAPI(t *testing.T) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer curve.Free()
+
+    order, err := openssl.BNToBigInt(curve.Order)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    x, err := math.GenRandModBN(curve)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer x.Free()
+
+    bigX, err := x.BigInt()
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    z := math.NewZeroModBN(curve)
+    defer z.Free()
+
+    // Checks that z holds expected modulo the order.
+    check := func(name string, expected *big.Int) {
+        got, err := z.BigInt()
+        if err != nil {
+            t.Fatal(err)
+        }
+        if got.Cmp(new(big.Int).Mod(expected, order)) != 0 {
+            t.Errorf("%s: got %x, expected %x", name, got, expected)
+        }
+    }
+
+    t.Run("IsZero and IsOne", func(t *testing.T) {
+        if !z.IsZero() || z.IsOne() {
+            t.Error("NewZeroModBN should return zero")
+        }
+
+        err := z.SetUint64(1)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if z.IsZero() || !z.IsOne() {
+            t.Error("SetUint64(1) should set one")
+        }
+    })
+
+    t.Run("SetUint64", func(t *testing.T) {
+        err := z.SetUint64(1 << 63 + 12345)
+        if err != nil {
+            t.Fatal(err)
+        }
+        check("SetUint64", new(big.Int).SetUint64(1 << 63 + 12345))
+    })
+
+    t.Run("BigIntToModBN", func(t *testing.T) {
+        for _, value := range []*big.Int{bigX, new(big.Int).Neg(bigX),
+                new(big.Int).Add(bigX, order), big.NewInt(0)} {
+            bn, err := math.BigIntToModBN(value, curve)
+            if err != nil {
+                t.Fatal(err)
+            }
+            defer bn.Free()
+
+            got, err := bn.BigInt()
+            if err != nil {
+                t.Fatal(err)
+            }
+            if got.Cmp(new(big.Int).Mod(value, order)) != 0 {
+                t.Errorf("Got: %x, Expected: %x mod n", got, value)
+            }
+        }
+    })
+
+    t.Run("PowUint64", func(t *testing.T) {
+        for _, e := range []uint64{0, 1, 2, 3, 65537, 1 << 64 - 1} {
+            err := z.PowUint64(x, e)
+            if err != nil {
+                t.Fatal(err)
+            }
+            check("PowUint64", new(big.Int).Exp(bigX, new(big.Int).SetUint64(e), order))
+        }
+    })
+
+    t.Run("Sqrt and Legendre", func(t *testing.T) {
+        square := math.NewZeroModBN(curve)
+        defer square.Free()
+
+        err := square.Mul(x, x)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        err = z.Sqrt(square)
+        if err != nil {
+            t.Fatal(err)
+        }
+        // The root is x or -x.
+        got, err := z.BigInt()
+        if err != nil {
+            t.Fatal(err)
+        }
+        if got.Cmp(bigX) != 0 && got.Cmp(new(big.Int).Sub(order, bigX)) != 0 {
+            t.Errorf("Got: %x, Expected: +/- %x", got, bigX)
+        }
+
+        symbol, err := square.Legendre()
+        if err != nil || symbol != 1 {
+            t.Error("Got:", symbol, err, "Expected: 1")
+        }
+
+        // The smallest non square modulo the order.
+        nonSquare := big.NewInt(2)
+        for big.Jacobi(nonSquare, order) != -1 {
+            nonSquare.Add(nonSquare, big.NewInt(1))
+        }
+        err = z.SetUint64(nonSquare.Uint64())
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        symbol, err = z.Legendre()
+        if err != nil || symbol != -1 {
+            t.Error("Got:", symbol, err, "Expected: -1")
+        }
+
+        err = square.Sqrt(z)
+        if !errors.Is(err, math.ErrNotASquare) {
+            t.Error("Got:", err, "Expected:", math.ErrNotASquare)
+        }
+
+        err = z.SetUint64(0)
+        if err != nil {
+            t.Fatal(err)
+        }
+        symbol, err = z.Legendre()
+        if err != nil || symbol != 0 {
+            t.Error("Got:", symbol, err, "Expected: 0")
+        }
+    })
+
+    t.Run("WideBytesToModBN", func(t *testing.T) {
+        wide := make([]byte, math.WideBytesLength)
+        for i := range wide {
+            wide[i] = 0xff
+        }
+
+        bn, err := math.WideBytesToModBN(wide, curve)
+        if err != nil {
+            t.Fatal(err)
+        }
+        defer bn.Free()
+
+        got, err := bn.BigInt()
+        if err != nil {
+            t.Fatal(err)
+        }
+        expected := new(big.Int).Mod(new(big.Int).SetBytes(wide), order)
+        if got.Cmp(expected) != 0 {
+            t.Errorf("Got: %x, Expected: %x", got, expected)
+        }
+
+        _, err = math.WideBytesToModBN(wide[:32], curve)
+        if !errors.Is(err, math.ErrInvalidEncoding) {
+            t.Error("Got:", err, "Expected:", math.ErrInvalidEncoding)
+        }
+    })
+}
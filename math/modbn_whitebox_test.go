@@ -239,8 +239,8 @@ func TestHash2BN(t *testing.T) {
 
 func TestBytesToModBN(t *testing.T) {
     t.Run("normal", func(t *testing.T) {
-        bs := make([]byte, 4)
-        binary.BigEndian.PutUint32(bs, 999111777)
+        bs := make([]byte, 32)
+        binary.BigEndian.PutUint32(bs[28:], 999111777)
 
         curve, err := openssl.NewCurve(openssl.SECP256K1)
         if err != nil {
@@ -269,6 +269,22 @@ func TestBytesToModBN(t *testing.T) {
         }
         modbn.Free()
     })
+
+    t.Run("short", func(t *testing.T) {
+        bs := make([]byte, 4)
+        binary.BigEndian.PutUint32(bs, 999111777)
+
+        curve, err := openssl.NewCurve(openssl.SECP256K1)
+        if err != nil {
+            t.Error(err)
+        }
+        defer curve.Free()
+
+        _, err = BytesToModBN(bs, curve)
+        if err == nil {
+            t.Error("A non-canonical byte array returned a valid bignum.")
+        }
+    })
 }
 
 func TestBytesToBytesModBN(t *testing.T) {
@@ -289,6 +305,10 @@ func TestBytesToBytesModBN(t *testing.T) {
         t.Error(err)
     }
 
+    if uint(len(bytes)) != ExpectedBytesLength(curve) {
+        t.Error("The ModBigNum was not serialized with a fixed length")
+    }
+
     newmodbn, err := BytesToModBN(bytes, curve)
     if err != nil {
         t.Error(err)
@@ -650,7 +670,7 @@ func TestMod(t *testing.T) {
     }
     defer curve.Free()
 
-    modbn1, err := IntToModBN(768, curve)
+    modbn1, err := IntToModBN(700, curve)
     if err != nil {
         t.Error(err)
     }
@@ -666,4 +686,14 @@ func TestMod(t *testing.T) {
     if err != nil {
         t.Error(err)
     }
+
+    expected, err := IntToModBN(188, curve)
+    if err != nil {
+        t.Error(err)
+    }
+    defer expected.Free()
+
+    if !modbn1.Equals(expected) {
+        t.Error("Got:", openssl.BNToDecStr(modbn1.Bignum), "Expected: 188")
+    }
 }
@@ -17,20 +17,45 @@
 package math
 
 import (
+    "sync/atomic"
     "github.com/nucypher/goUmbral/openssl"
 )
 
+// Represents the public parameters of Umbral: a curve, its generator G,
+// a second generator U, and the hash function from which U and all the
+// other derived values are computed.
+//
+// UmbralParameters are safe for concurrent use by multiple goroutines once
+// they are built, and precomputed if they are to be, as long as none of
+// them modifies G or U.
+// They are reference counted like Curves: G and U are only freed with the
+// last reference, and they keep the Curve alive until then.
+
 type UmbralParameters struct {
     Curve *openssl.Curve
     Size uint
     G *Point
     U *Point
+    Hash HashFunction
+
+    // The number of references to the parameters, updated atomically.
+    refs int32
+    // Set once Free dropped the reference of NewUmbralParameters.
+    freed int32
 }
 
+// Returns the UmbralParameters of the curve with BLAKE2b-512,
+// which are compatible with pyUmbral.
 func NewUmbralParameters(curve *openssl.Curve) (*UmbralParameters, error) {
-    var params UmbralParameters
+    return NewUmbralParametersWithHash(curve, BLAKE2b512)
+}
+
+// Returns the UmbralParameters of the curve with the given hash function.
+func NewUmbralParametersWithHash(curve *openssl.Curve, hash HashFunction) (*UmbralParameters, error) {
+    params := UmbralParameters{refs: 1}
     params.Curve = curve
     params.Size = curve.FieldOrderSize()
+    params.Hash = hash
 
     params.G = GetGeneratorFromCurve(curve)
     gBytes, err := params.G.ToBytes(true)
@@ -44,11 +69,45 @@ func NewUmbralParameters(curve *openssl.Curve) (*UmbralParameters, error) {
 
     params.U, err = UnsafeHashToPoint(gBytes, &params, parametersSeed)
     if err != nil {
+        params.G.Free()
         return nil, err
     }
     return &params, nil
 }
 
+// UmbralParameters.Retain() adds a reference to the parameters, which must
+// be dropped with Release once the calling function no longer uses them.
+func (m *UmbralParameters) Retain() *UmbralParameters {
+    if atomic.AddInt32(&m.refs, 1) <= 1 {
+        panic("Retain called on freed parameters")
+    }
+    return m
+}
+
+// UmbralParameters.Release() drops a reference taken with Retain,
+// and frees G and U if it was the last one.
+func (m *UmbralParameters) Release() {
+    refs := atomic.AddInt32(&m.refs, -1)
+    if refs < 0 {
+        panic("Release called on freed parameters")
+    }
+    if refs == 0 {
+        m.G.Free()
+        m.U.Free()
+    }
+}
+
+// UmbralParameters.Free() drops the reference returned by NewUmbralParameters.
+//
+// It is safe to call Free more than once. The parameters have no finalizer:
+// if they are not freed, G and U are only freed by their own finalizers.
+func (m *UmbralParameters) Free() {
+    if m == nil || !atomic.CompareAndSwapInt32(&m.freed, 0, 1) {
+        return
+    }
+    m.Release()
+}
+
 func (m *UmbralParameters) Equals(other *UmbralParameters) bool {
     // TODO: This is not comparing the order, which currently is an OpenSSL pointer
 
@@ -56,6 +115,10 @@ func (m *UmbralParameters) Equals(other *UmbralParameters) bool {
 
     eSize := (m.Size == other.Size)
 
+    if m.Hash != other.Hash {
+        return false
+    }
+
     eG, err := m.G.Equals(other.G)
     if err != nil {
         // Could return the error.
@@ -70,3 +133,29 @@ func (m *UmbralParameters) Equals(other *UmbralParameters) bool {
 
     return eCurve && eSize && eG && eU
 }
+
+// UmbralParameters.Precompute() builds the tables of the multiples of G and U,
+// so that Point.Mul with G or U uses a fixed-base multiplication, which is
+// several times faster than the generic one.
+//
+// The tables take about 100 kB per Point on a 256 bit curve. They are held
+// by G and U, and are dropped if G or U is modified.
+//
+// Precompute sets the tables without any synchronization, so it must return
+// before the parameters are shared between goroutines, and must not be
+// called again once they are.
+func (m *UmbralParameters) Precompute() error {
+    gTable, err := newFixedBaseTable(m.G)
+    if err != nil {
+        return err
+    }
+
+    uTable, err := newFixedBaseTable(m.U)
+    if err != nil {
+        return err
+    }
+
+    m.G.table = gTable
+    m.U.table = uTable
+    return nil
+}
@@ -0,0 +1,289 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package math_test
+
+import (
+    "testing"
+    "github.com/nucypher/goUmbral/math"
+    "github.com/nucypher/goUmbral/openssl"
+)
+
+var hashFunctions = []math.HashFunction{math.BLAKE2b512, math.SHA256,
+    math.SHA512, math.SHA3_256, math.Keccak256}
+
+func TestUmbralParametersWithHash(t *testing.T) {
+    secp256k1, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Error(err)
+    }
+    defer secp256k1.Free()
+
+    p256, err := openssl.NewCurve(openssl.SECP256R1)
+    if err != nil {
+        t.Error(err)
+    }
+    defer p256.Free()
+
+    p384, err := openssl.NewCurve(openssl.SECP384R1)
+    if err != nil {
+        t.Error(err)
+    }
+    defer p384.Free()
+
+    for _, curve := range []*openssl.Curve{secp256k1, p256, p384} {
+        var allParams []*math.UmbralParameters
+        for _, hash := range hashFunctions {
+            params, err := math.NewUmbralParametersWithHash(curve, hash)
+            if err != nil {
+                t.Error(hash, err)
+                continue
+            }
+
+            for _, other := range allParams {
+                if params.Equals(other) {
+                    t.Error("The parameters of", hash, "and", other.Hash, "were equal")
+                }
+
+                equal, err := params.U.Equals(other.U)
+                if err != nil {
+                    t.Error(err)
+                }
+                if equal {
+                    t.Error("The U of", hash, "and", other.Hash, "were equal")
+                }
+            }
+            allParams = append(allParams, params)
+
+            data := []byte("attack at dawn")
+            h, err := math.HashItemsToModBN(params, nil, data, params.U)
+            if err != nil {
+                t.Error(hash, err)
+                continue
+            }
+            h.Free()
+        }
+    }
+}
+
+func TestDefaultUmbralParameters(t *testing.T) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Error(err)
+    }
+    defer curve.Free()
+
+    params, err := math.NewUmbralParameters(curve)
+    if err != nil {
+        t.Error(err)
+    }
+
+    blake2bParams, err := math.NewUmbralParametersWithHash(curve, math.BLAKE2b512)
+    if err != nil {
+        t.Error(err)
+    }
+
+    if params.Hash != math.BLAKE2b512 || !params.Equals(blake2bParams) {
+        t.Error("The default parameters do not use BLAKE2b-512")
+    }
+}
+
+func TestUmbralParametersPrecompute(t *testing.T) {
+    secp256k1, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer secp256k1.Free()
+
+    p256, err := openssl.NewCurve(openssl.SECP256R1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer p256.Free()
+
+    p384, err := openssl.NewCurve(openssl.SECP384R1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer p384.Free()
+
+    for _, curve := range []*openssl.Curve{secp256k1, p256, p384} {
+        params, err := math.NewUmbralParameters(curve)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        precomputed, err := math.NewUmbralParameters(curve)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        err = precomputed.Precompute()
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if !params.Equals(precomputed) {
+            t.Error("The precomputed parameters were not equal to the parameters")
+        }
+
+        scalars := make([]*math.ModBigNum, 0, 10)
+        for i := 0; i < 8; i++ {
+            scalar, err := math.GenRandModBN(curve)
+            if err != nil {
+                t.Fatal(err)
+            }
+            defer scalar.Free()
+            scalars = append(scalars, scalar)
+        }
+
+        one, err := math.IntToModBN(1, curve)
+        if err != nil {
+            t.Fatal(err)
+        }
+        defer one.Free()
+
+        // A zero scalar gives the point at infinity.
+        zero, err := one.Copy()
+        if err != nil {
+            t.Fatal(err)
+        }
+        defer zero.Free()
+
+        err = zero.Sub(zero, one)
+        if err != nil {
+            t.Fatal(err)
+        }
+        scalars = append(scalars, one, zero)
+
+        bases := [][2]*math.Point{{params.G, precomputed.G}, {params.U, precomputed.U}}
+        for _, scalar := range scalars {
+            for _, base := range bases {
+                expected, err := base[0].Copy()
+                if err != nil {
+                    t.Fatal(err)
+                }
+                defer expected.Free()
+
+                err = expected.Mul(base[0], scalar)
+                if err != nil {
+                    t.Error(err)
+                }
+
+                result, err := base[0].Copy()
+                if err != nil {
+                    t.Fatal(err)
+                }
+                defer result.Free()
+
+                err = result.Mul(base[1], scalar)
+                if err != nil {
+                    t.Error(err)
+                }
+
+                equal, err := result.Equals(expected)
+                if err != nil {
+                    t.Error(err)
+                }
+                if !equal {
+                    t.Error("The precomputed product was not equal to the product")
+                }
+            }
+        }
+    }
+}
+
+func benchmarkMul(b *testing.B, precompute bool, useU bool) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        b.Fatal(err)
+    }
+    defer curve.Free()
+
+    params, err := math.NewUmbralParameters(curve)
+    if err != nil {
+        b.Fatal(err)
+    }
+
+    if precompute {
+        err = params.Precompute()
+        if err != nil {
+            b.Fatal(err)
+        }
+    }
+
+    base := params.G
+    if useU {
+        base = params.U
+    }
+
+    scalar, err := math.GenRandModBN(curve)
+    if err != nil {
+        b.Fatal(err)
+    }
+    defer scalar.Free()
+
+    result, err := math.GenRandPoint(curve)
+    if err != nil {
+        b.Fatal(err)
+    }
+    defer result.Free()
+
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        err = result.Mul(base, scalar)
+        if err != nil {
+            b.Fatal(err)
+        }
+    }
+}
+
+func BenchmarkMulG(b *testing.B) {
+    benchmarkMul(b, false, false)
+}
+
+func BenchmarkMulGPrecomputed(b *testing.B) {
+    benchmarkMul(b, true, false)
+}
+
+func BenchmarkMulU(b *testing.B) {
+    benchmarkMul(b, false, true)
+}
+
+func BenchmarkMulUPrecomputed(b *testing.B) {
+    benchmarkMul(b, true, true)
+}
+
+func BenchmarkPrecompute(b *testing.B) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        b.Fatal(err)
+    }
+    defer curve.Free()
+
+    params, err := math.NewUmbralParameters(curve)
+    if err != nil {
+        b.Fatal(err)
+    }
+
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        err = params.Precompute()
+        if err != nil {
+            b.Fatal(err)
+        }
+    }
+}
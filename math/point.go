@@ -17,19 +17,33 @@
 package math
 
 import (
+    "bytes"
     "errors"
     "math"
     "math/big"
+    "runtime"
     "encoding/binary"
-    "golang.org/x/crypto/blake2b"
     "github.com/nucypher/goUmbral/openssl"
 )
 
 // Represents an OpenSSL EC_POINT with a specific eliptic curve.
+//
+// A Point may also hold a table of its multiples, as G and U do after
+// UmbralParameters.Precompute(), which speeds up Point.Mul with it.
 
 type Point struct {
    ECPoint openssl.ECPoint
    Curve *openssl.Curve
+
+   table *fixedBaseTable
+
+   // The reference to Curve held until Free, if the Point was built
+   // by this package.
+   curveRef *openssl.Curve
+
+   // Whether a finalizer is set on the Point, which is only the case
+   // for the ones allocated by this package.
+   finalized bool
 }
 
 // Generate a new Point struct based on the arguments provided.
@@ -48,7 +62,7 @@ func NewPoint(point openssl.ECPoint, curve *openssl.Curve) (*Point, error) {
         }
         return newPoint, err
     }
-    return &Point{point, curve}, err
+    return newPoint(point, curve), err
 }
 
 // Returns the size (in bytes) of a compressed Point given a curve.
@@ -80,20 +94,22 @@ func GenRandPoint(curve *openssl.Curve) (*Point, error) {
 
     randModBN, err := GenRandModBN(curve)
     if err != nil {
+        openssl.FreeECPoint(randPoint)
         return nil, err
     }
     defer randModBN.Free()
 
-    ctx := openssl.NewBNCtx()
-    defer openssl.FreeBNCtx(ctx)
+    ctx := openssl.GetBNCtx()
+    defer openssl.PutBNCtx(ctx)
 
     result := openssl.MulECP(curve.Group, randPoint, nil,
         curve.Generator, randModBN.Bignum, ctx)
     if result != nil {
+        openssl.FreeECPoint(randPoint)
         return nil, result
     }
 
-    return &Point{randPoint, curve}, nil
+    return newPoint(randPoint, curve), nil
 }
 
 // Returns a Point object from the given affine coordinates.
@@ -102,21 +118,26 @@ func AffineToPoint(affineX, affineY *big.Int, curve *openssl.Curve) (*Point, err
     if err != nil {
         return nil, err
     }
+    defer openssl.FreeBigNum(x)
+
     y, err := openssl.BigIntToBN(affineY)
     if err != nil {
         return nil, err
     }
+    defer openssl.FreeBigNum(y)
 
     point, err := openssl.GetECPointFromAffine(x, y, curve)
     if err != nil {
         return nil, err
     }
 
-    return &Point{point, curve}, nil
+    return newPoint(point, curve), nil
 }
 
 // Returns an x and y coordinate of the Point as a Go big.Int.
-func (m Point) ToAffine() (*big.Int, *big.Int, error) {
+func (m *Point) ToAffine() (*big.Int, *big.Int, error) {
+    defer keepAlive(m)
+
     xBN, yBN, err := openssl.GetAffineCoordsFromECPoint(m.ECPoint, m.Curve)
     if err != nil {
         return nil, nil, err
@@ -137,7 +158,7 @@ func (m Point) ToAffine() (*big.Int, *big.Int, error) {
 
 func BytesToPoint(data []byte, curve *openssl.Curve) (*Point, error) {
     if len(data) == 0 {
-        return nil, errors.New("No bytes failure")
+        return nil, &EncodingError{Reason: "No bytes failure"}
     }
 
     compressedSize := PointLength(curve, true)
@@ -145,14 +166,14 @@ func BytesToPoint(data []byte, curve *openssl.Curve) (*Point, error) {
     // Check if compressed
     if data[0] == 2 || data[0] == 3 {
         if uint(len(data)) != compressedSize {
-            return nil, errors.New("X coordinate too large for curve")
+            return nil, &EncodingError{Reason: "X coordinate too large for curve"}
         }
 
-        // affineX might need to be freed.
         affineX, err := openssl.BytesToBN(data[1:])
         if err != nil {
             return nil, err
         }
+        defer openssl.FreeBigNum(affineX)
 
         typeY := data[0] - 2
 
@@ -161,15 +182,16 @@ func BytesToPoint(data []byte, curve *openssl.Curve) (*Point, error) {
             return nil, err
         }
 
-        ctx := openssl.NewBNCtx()
-        defer openssl.FreeBNCtx(ctx)
+        ctx := openssl.GetBNCtx()
+        defer openssl.PutBNCtx(ctx)
 
         result := openssl.SetCompressedCoordsECP(
             curve.Group, point, affineX, int(typeY), ctx)
         if result != nil {
+            openssl.FreeECPoint(point)
             return nil, result
         }
-        return &Point{point, curve}, nil
+        return checkCanonical(newPoint(point, curve), data, true)
     } else if data[0] == 4 {
         // Handle uncompressed point
         coordSize := compressedSize - 1
@@ -177,7 +199,7 @@ func BytesToPoint(data []byte, curve *openssl.Curve) (*Point, error) {
         uncompressedSize := 1 + (2 * coordSize)
 
         if uint(len(data)) != uncompressedSize {
-            return nil, errors.New("Uncompressed point does not have right size")
+            return nil, &EncodingError{Reason: "Uncompressed point does not have right size"}
         }
         affineX := big.NewInt(0)
         affineY := big.NewInt(0)
@@ -185,42 +207,82 @@ func BytesToPoint(data []byte, curve *openssl.Curve) (*Point, error) {
         affineX.SetBytes(data[1:coordSize+1])
         affineY.SetBytes(data[1+coordSize:])
 
-        return AffineToPoint(affineX, affineY, curve)
+        point, err := AffineToPoint(affineX, affineY, curve)
+        if err != nil {
+            return nil, err
+        }
+        return checkCanonical(point, data, false)
     } else {
-        return nil, errors.New("Invalid point serialization")
+        return nil, &EncodingError{Reason: "Invalid point serialization"}
+    }
+}
+
+// Returns the Point if data is its canonical serialization,
+// and frees it otherwise.
+//
+// OpenSSL reduces coordinates modulo the field prime, so a coordinate
+// that is not fully reduced would otherwise be accepted.
+func checkCanonical(point *Point, data []byte, isCompressed bool) (*Point, error) {
+    encoded, err := point.ToBytes(isCompressed)
+    if err != nil {
+        point.Free()
+        return nil, err
     }
+    if !bytes.Equal(encoded, data) {
+        point.Free()
+        return nil, &EncodingError{Reason: "The point is not canonically encoded"}
+    }
+    return point, nil
 }
 
 // Returns the Point serialized as bytes.
 // It will return a compressed form if isCompressed is set to True.
-func (m Point) ToBytes(isCompressed bool) ([]byte, error) {
+//
+// The coordinates are left padded with zeros to the size of the field,
+// so the output is always PointLength bytes long.
+func (m *Point) ToBytes(isCompressed bool) ([]byte, error) {
     x, y, err := m.ToAffine()
     if err != nil {
         return nil, err
     }
+    return affineToBytes(x, y, m.Curve, isCompressed), nil
+}
 
-    if isCompressed {
-        yBytes := y.Bytes()
-        yBit := (yBytes[len(yBytes) - 1] & byte(1)) + 2
+// Returns the serialization of the Point with the affine coordinates x and y.
+func affineToBytes(x, y *big.Int, curve *openssl.Curve, isCompressed bool) []byte {
+    coordSize := curve.FieldOrderSize()
 
-        var data []byte
-        data = append(data, yBit)
-        return append(data, x.Bytes()...), nil
+    if isCompressed {
+        data := make([]byte, PointLength(curve, true))
+        data[0] = byte(y.Bit(0)) + 2
+        putPadded(data[1:], x)
+        return data
     } else {
-        var data []byte
-        data = append(data, byte(4))
-        data = append(data, x.Bytes()...)
-        return append(data, y.Bytes()...), nil
+        data := make([]byte, PointLength(curve, false))
+        data[0] = byte(4)
+        putPadded(data[1:1 + coordSize], x)
+        putPadded(data[1 + coordSize:], y)
+        return data
     }
 }
 
+// Copies the big endian bytes of x to the end of buf, which must be
+// zeroed and long enough to hold them.
+func putPadded(buf []byte, x *big.Int) {
+    data := x.Bytes()
+    copy(buf[len(buf) - len(data):], data)
+}
+
+// Returns the generator of the curve.
+//
+// The Point shares the EC_POINT of the curve, which is not freed by Point.Free().
 func GetGeneratorFromCurve(curve *openssl.Curve) *Point {
-    // Consider making a copy of this point
-    // so there are not any double frees.
-    return &Point{curve.Generator, curve}
+    return newPoint(curve.Generator, curve)
 }
 
 func (m *Point) Equals(other *Point) (bool, error) {
+    defer keepAlive(m, other)
+
     if m.ECPoint == nil || other.ECPoint == nil {
         return false, errors.New("One of the EC_POINTs was null")
     }
@@ -228,8 +290,8 @@ func (m *Point) Equals(other *Point) (bool, error) {
         return false, errors.New("The curve group is null")
     }
 
-    ctx := openssl.NewBNCtx()
-    defer openssl.FreeBNCtx(ctx)
+    ctx := openssl.GetBNCtx()
+    defer openssl.PutBNCtx(ctx)
 
     result, err := openssl.CmpECP(m.Curve.Group, m.ECPoint, other.ECPoint, ctx)
     if err != nil {
@@ -242,15 +304,36 @@ func (m *Point) Equals(other *Point) (bool, error) {
 // It will then set z to the result of that operation.
 //
 // x, y, and z must use the same curve and must be initialized.
+// If x holds a table of its multiples, the product is computed from
+// the table with lookups and additions that do not depend on y.
+// The result is then decoded by OpenSSL, and the point at infinity
+// is left to the generic multiplication, so this is not a guarantee
+// of constant time.
 //
 // Mul will return the error if one occurred, and nil otherwise.
 func (z *Point) Mul(x *Point, y *ModBigNum) error {
+    defer keepAlive(z, x, y)
+
     if !x.Curve.Equals(y.Curve) {
-        return errors.New("The points do not share the same curve.")
+        return ErrCurveMismatch
+    }
+
+    table := x.table
+    z.table = nil
+    if table != nil {
+        point, err := table.mul(y)
+        if err != nil {
+            return err
+        }
+        // The point at infinity is left to OpenSSL.
+        if point != nil {
+            defer point.Free()
+            return openssl.CopyECP(z.ECPoint, point.ECPoint)
+        }
     }
 
-    ctx := openssl.NewBNCtx()
-    defer openssl.FreeBNCtx(ctx)
+    ctx := openssl.GetBNCtx()
+    defer openssl.PutBNCtx(ctx)
 
     result := openssl.MulECP(x.Curve.Group, z.ECPoint, nil,
         x.ECPoint, y.Bignum, ctx)
@@ -260,6 +343,66 @@ func (z *Point) Mul(x *Point, y *ModBigNum) error {
     return nil
 }
 
+// Point.MulAdd() will perform (a * G + b * p) where G is the generator
+// of the curve, in a single OpenSSL call.
+// It will then set z to the result of that operation.
+//
+// a, p, b, and z must use the same curve and must be initialized.
+//
+// MulAdd will return the error if one occurred, and nil otherwise.
+func (z *Point) MulAdd(a *ModBigNum, p *Point, b *ModBigNum) error {
+    defer keepAlive(z, a, p, b)
+
+    if !a.Curve.Equals(p.Curve) || !b.Curve.Equals(p.Curve) {
+        return ErrCurveMismatch
+    }
+    z.table = nil
+
+    ctx := openssl.GetBNCtx()
+    defer openssl.PutBNCtx(ctx)
+
+    return openssl.MulECP(p.Curve.Group, z.ECPoint, a.Bignum, p.ECPoint, b.Bignum, ctx)
+}
+
+// Returns the sum of points[i] * scalars[i], computed in a single
+// OpenSSL call which shares the doublings between all the products.
+//
+// The points and scalars must all use the same curve.
+// The Point must be freed by the calling function.
+func MultiScalarMul(points []*Point, scalars []*ModBigNum) (*Point, error) {
+    defer keepAlive(points, scalars)
+
+    if len(points) == 0 || len(points) != len(scalars) {
+        return nil, errors.New("There must be as many scalars as points, and at least one")
+    }
+    curve := points[0].Curve
+
+    ecPoints := make([]openssl.ECPoint, len(points))
+    bignums := make([]openssl.BigNum, len(scalars))
+    for i := range points {
+        if !points[i].Curve.Equals(curve) || !scalars[i].Curve.Equals(curve) {
+            return nil, ErrCurveMismatch
+        }
+        ecPoints[i] = points[i].ECPoint
+        bignums[i] = scalars[i].Bignum
+    }
+
+    result, err := openssl.NewECPoint(curve)
+    if err != nil {
+        return nil, err
+    }
+
+    ctx := openssl.GetBNCtx()
+    defer openssl.PutBNCtx(ctx)
+
+    err = openssl.MulsECP(curve.Group, result, nil, ecPoints, bignums, ctx)
+    if err != nil {
+        openssl.FreeECPoint(result)
+        return nil, err
+    }
+    return newPoint(result, curve), nil
+}
+
 // Point.Add() will perform (x + y).
 // It will then set z to the result of that operation.
 //
@@ -267,12 +410,15 @@ func (z *Point) Mul(x *Point, y *ModBigNum) error {
 //
 // Add will return the error if one occurred, and nil otherwise.
 func (z *Point) Add(x, y *Point) error {
+    defer keepAlive(z, x, y)
+
     if !x.Curve.Equals(y.Curve) {
-        return errors.New("The points do not share the same curve.")
+        return ErrCurveMismatch
     }
+    z.table = nil
 
-    ctx := openssl.NewBNCtx()
-    defer openssl.FreeBNCtx(ctx)
+    ctx := openssl.GetBNCtx()
+    defer openssl.PutBNCtx(ctx)
 
     result := openssl.AddECP(x.Curve.Group, z.ECPoint, x.ECPoint, y.ECPoint, ctx)
     if result != nil {
@@ -290,7 +436,7 @@ func (z *Point) Add(x, y *Point) error {
 // Sub will return the error if one occurred, and nil otherwise.
 func (z *Point) Sub(x, y *Point) error {
     if !x.Curve.Equals(y.Curve) {
-        return errors.New("The points do not share the same curve.")
+        return ErrCurveMismatch
     }
 
     // Performs a subtraction on two EC_POINTS by adding by the inverse.
@@ -320,33 +466,30 @@ func (z *Point) Sub(x, y *Point) error {
 //
 // Invert will return the error if one occurred, and nil otherwise.
 func (z *Point) Invert(x *Point) error {
-    ctx := openssl.NewBNCtx()
-    defer openssl.FreeBNCtx(ctx)
+    defer keepAlive(z, x)
 
-    inv, err := x.Copy()
+    ctx := openssl.GetBNCtx()
+    defer openssl.PutBNCtx(ctx)
+
+    z.table = nil
+    err := openssl.CopyECP(z.ECPoint, x.ECPoint)
     if err != nil {
         return err
     }
 
-    result := openssl.InvertECP(x.Curve.Group, inv.ECPoint, ctx)
-    if result != nil {
-        return result
-    }
-    if z.ECPoint != nil {
-        openssl.FreeECPoint(z.ECPoint)
-    }
-    z.ECPoint = inv.ECPoint
-
-    return nil
+    return openssl.InvertECP(x.Curve.Group, z.ECPoint, ctx)
 }
 
 // Hashes arbitrary data into a valid EC point of the specified curve,
 // using the try-and-increment method.
 // It admits an optional label as an additional input to the hash function.
-// It uses BLAKE2b (with a digest size of 64 bytes) as the internal hash function.
+// It uses the hash function of the parameters, whose digest is extended with
+// expand_message_xmd if it is shorter than a compressed point. With the default
+// BLAKE2b-512, the 64 byte digest is used as it is, as in pyUmbral.
 
 // WARNING: Do not use when the input data is secret, as this implementation is not
 // in constant time, and hence, it is not safe with respect to timing attacks.
+// Use HashToCurve for secret inputs.
 // TODO: Check how to uniformly generate ycoords. Currently, it only outputs points
 // where ycoord is even (i.e., starting with 0x02 in compressed notation)
 func UnsafeHashToPoint(data []byte, params *UmbralParameters, label []byte) (*Point, error) {
@@ -373,7 +516,10 @@ func UnsafeHashToPoint(data []byte, params *UmbralParameters, label []byte) (*Po
 
         dataCopy = append(dataCopy, bs...)
 
-        hash := blake2b.Sum512(dataCopy)
+        hash, err := params.Hash.expand(dataCopy, 1 + int(params.Size))
+        if err != nil {
+            return nil, err
+        }
 
         var sign []byte = make([]byte, 1)
         if hash[0] & 1 == 0 {
@@ -387,34 +533,65 @@ func UnsafeHashToPoint(data []byte, params *UmbralParameters, label []byte) (*Po
         point, err := BytesToPoint(compressedPoint, params.Curve)
 
         if err != nil {
-            // TODO: Catching Exceptions
-            // We want to catch specific InternalExceptions:
-            // - Point not in the curve (code 107)
-            // - Invalid compressed point (code 110)
-            // https://github.com/openssl/openssl/blob/master/include/openssl/ecerr.h#L228
-            // return Point{}, err
-            continue
-        } else {
-            return point, nil
+            // Try the next counter if the x coordinate is not on the curve
+            // or is not reduced, but not on internal failures.
+            if errors.Is(err, ErrPointNotOnCurve) || errors.Is(err, ErrInvalidEncoding) {
+                continue
+            }
+            return nil, err
         }
+        return point, nil
     }
 
     // Only happens with probability 2^(-32)
-    return nil, errors.New("Could not hash input into the curve")
+    return nil, ErrNoPointFound
 }
 
 func (m *Point) Copy() (*Point, error) {
+    defer keepAlive(m)
+
     // Deep copy of a Point EXCLUDING the curve.
     point, err := openssl.DupECP(m.ECPoint, m.Curve.Group)
     if err != nil {
         return nil, err
     }
 
-    return &Point{point, m.Curve}, nil
+    return newPoint(point, m.Curve), nil
 }
 
+// Point.Free() clears and frees the EC_POINT of the Point.
+//
+// It is safe to call Free more than once, and on a nil Point.
+// A Point returned by this package that is not freed is freed when
+// it is garbage collected.
+// The generator of a curve belongs to the curve, and is not freed.
 func (m *Point) Free() {
-    openssl.FreeECPoint(m.ECPoint)
-    // Do not free the curve.
-    // m.Curve.Free()
+    if m == nil {
+        return
+    }
+    if m.ECPoint != nil {
+        if m.Curve == nil || m.ECPoint != m.Curve.Generator {
+            openssl.FreeECPoint(m.ECPoint)
+        }
+        m.ECPoint = nil
+    }
+    // Do not free the curve, only drop the reference to it.
+    if m.curveRef != nil {
+        m.curveRef.Release()
+        m.curveRef = nil
+    }
+    // m may be a field of another struct, on which a finalizer cannot be set.
+    if m.finalized {
+        runtime.SetFinalizer(m, nil)
+        m.finalized = false
+    }
+}
+
+// Returns a Point which holds a reference to the curve,
+// and frees the EC_POINT when it is garbage collected.
+func newPoint(ecPoint openssl.ECPoint, curve *openssl.Curve) *Point {
+    m := &Point{ECPoint: ecPoint, Curve: curve, curveRef: curve.Retain(),
+        finalized: true}
+    runtime.SetFinalizer(m, (*Point).Free)
+    return m
 }
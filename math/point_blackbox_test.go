@@ -320,3 +320,259 @@ func TestUnsafeHashToPoint(t *testing.T) {
         }
     }
 }
+
+func TestMultiScalarMul(t *testing.T) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Error(err)
+    }
+    defer curve.Free()
+
+    points := make([]*math.Point, 4)
+    scalars := make([]*math.ModBigNum, 4)
+    for i := range points {
+        points[i], err = math.GenRandPoint(curve)
+        if err != nil {
+            t.Error(err)
+        }
+        defer points[i].Free()
+
+        scalars[i], err = math.GenRandModBN(curve)
+        if err != nil {
+            t.Error(err)
+        }
+        defer scalars[i].Free()
+    }
+
+    result, err := math.MultiScalarMul(points, scalars)
+    if err != nil {
+        t.Error(err)
+    }
+    defer result.Free()
+
+    expected, err := naiveMultiScalarMul(points, scalars)
+    if err != nil {
+        t.Error(err)
+    }
+    defer expected.Free()
+
+    equal, err := result.Equals(expected)
+    if err != nil {
+        t.Error(err)
+    }
+    if !equal {
+        t.Error("The multi-scalar multiplication was not equal to the chained one")
+    }
+
+    _, err = math.MultiScalarMul(points, scalars[:3])
+    if err == nil {
+        t.Error("Mismatched points and scalars were multiplied")
+    }
+}
+
+func TestPointMulAdd(t *testing.T) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Error(err)
+    }
+    defer curve.Free()
+
+    point, err := math.GenRandPoint(curve)
+    if err != nil {
+        t.Error(err)
+    }
+    defer point.Free()
+
+    a, err := math.GenRandModBN(curve)
+    if err != nil {
+        t.Error(err)
+    }
+    defer a.Free()
+
+    b, err := math.GenRandModBN(curve)
+    if err != nil {
+        t.Error(err)
+    }
+    defer b.Free()
+
+    result, err := point.Copy()
+    if err != nil {
+        t.Error(err)
+    }
+    defer result.Free()
+
+    err = result.MulAdd(a, point, b)
+    if err != nil {
+        t.Error(err)
+    }
+
+    g := math.GetGeneratorFromCurve(curve)
+    expected, err := naiveMultiScalarMul([]*math.Point{g, point}, []*math.ModBigNum{a, b})
+    if err != nil {
+        t.Error(err)
+    }
+    defer expected.Free()
+
+    equal, err := result.Equals(expected)
+    if err != nil {
+        t.Error(err)
+    }
+    if !equal {
+        t.Error("aG + bP was not equal to the chained computation")
+    }
+}
+
+// Returns the sum of points[i] * scalars[i] with one Mul and Add per point.
+func naiveMultiScalarMul(points []*math.Point, scalars []*math.ModBigNum) (*math.Point, error) {
+    result, err := points[0].Copy()
+    if err != nil {
+        return nil, err
+    }
+
+    err = result.Mul(points[0], scalars[0])
+    if err != nil {
+        result.Free()
+        return nil, err
+    }
+
+    tmp, err := points[0].Copy()
+    if err != nil {
+        result.Free()
+        return nil, err
+    }
+    defer tmp.Free()
+
+    for i := 1; i < len(points); i++ {
+        err = tmp.Mul(points[i], scalars[i])
+        if err != nil {
+            result.Free()
+            return nil, err
+        }
+
+        err = result.Add(result, tmp)
+        if err != nil {
+            result.Free()
+            return nil, err
+        }
+    }
+    return result, nil
+}
+
+func benchmarkPointsAndScalars(b *testing.B, curve *openssl.Curve, n int) ([]*math.Point, []*math.ModBigNum) {
+    points := make([]*math.Point, n)
+    scalars := make([]*math.ModBigNum, n)
+    var err error
+    for i := range points {
+        points[i], err = math.GenRandPoint(curve)
+        if err != nil {
+            b.Fatal(err)
+        }
+
+        scalars[i], err = math.GenRandModBN(curve)
+        if err != nil {
+            b.Fatal(err)
+        }
+    }
+    return points, scalars
+}
+
+func BenchmarkMultiScalarMul(b *testing.B) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        b.Fatal(err)
+    }
+    defer curve.Free()
+
+    points, scalars := benchmarkPointsAndScalars(b, curve, 3)
+
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        result, err := math.MultiScalarMul(points, scalars)
+        if err != nil {
+            b.Fatal(err)
+        }
+        result.Free()
+    }
+}
+
+func BenchmarkChainedMul(b *testing.B) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        b.Fatal(err)
+    }
+    defer curve.Free()
+
+    points, scalars := benchmarkPointsAndScalars(b, curve, 3)
+
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        result, err := naiveMultiScalarMul(points, scalars)
+        if err != nil {
+            b.Fatal(err)
+        }
+        result.Free()
+    }
+}
+
+func BenchmarkPointMulAdd(b *testing.B) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        b.Fatal(err)
+    }
+    defer curve.Free()
+
+    points, scalars := benchmarkPointsAndScalars(b, curve, 2)
+    result, err := points[0].Copy()
+    if err != nil {
+        b.Fatal(err)
+    }
+    defer result.Free()
+
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        err = result.MulAdd(scalars[0], points[1], scalars[1])
+        if err != nil {
+            b.Fatal(err)
+        }
+    }
+}
+
+func BenchmarkPointMulThenAdd(b *testing.B) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        b.Fatal(err)
+    }
+    defer curve.Free()
+
+    points, scalars := benchmarkPointsAndScalars(b, curve, 2)
+    g := math.GetGeneratorFromCurve(curve)
+    result, err := points[0].Copy()
+    if err != nil {
+        b.Fatal(err)
+    }
+    defer result.Free()
+
+    tmp, err := points[0].Copy()
+    if err != nil {
+        b.Fatal(err)
+    }
+    defer tmp.Free()
+
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        err = result.Mul(g, scalars[0])
+        if err != nil {
+            b.Fatal(err)
+        }
+
+        err = tmp.Mul(points[1], scalars[1])
+        if err != nil {
+            b.Fatal(err)
+        }
+
+        err = result.Add(result, tmp)
+        if err != nil {
+            b.Fatal(err)
+        }
+    }
+}
@@ -17,6 +17,8 @@
 package math
 
 import (
+    "fmt"
+    "math/big"
     "testing"
     "github.com/nucypher/goUmbral/openssl"
 )
@@ -211,6 +213,119 @@ func TestPointToFromBytes(t *testing.T) {
     })
 }
 
+func TestPointLeadingZeroToFromBytes(t *testing.T) {
+    p256, err := openssl.NewCurve(openssl.SECP256R1)
+    if err != nil {
+        t.Error(err)
+    }
+    defer p256.Free()
+
+    secp256k1, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Error(err)
+    }
+    defer secp256k1.Free()
+
+    p384, err := openssl.NewCurve(openssl.SECP384R1)
+    if err != nil {
+        t.Error(err)
+    }
+    defer p384.Free()
+
+    for _, curve := range []*openssl.Curve{p256, secp256k1, p384} {
+        nid := fmt.Sprintf("curve %d", curve.NID)
+
+        // About 1 in 256 points has an x coordinate with a leading zero byte.
+        var point *Point
+        for i := 0; i < 8192; i++ {
+            point, err = GenRandPoint(curve)
+            if err != nil {
+                t.Error(err)
+            }
+            x, _, err := point.ToAffine()
+            if err != nil {
+                t.Error(err)
+            }
+            if uint(len(x.Bytes())) < curve.FieldOrderSize() {
+                break
+            }
+            point.Free()
+            point = nil
+        }
+        if point == nil {
+            t.Error("No point with a leading zero byte was found on " + nid)
+            continue
+        }
+        defer point.Free()
+
+        for _, isCompressed := range []bool{true, false} {
+            bytes, err := point.ToBytes(isCompressed)
+            if err != nil {
+                t.Error(err)
+            }
+
+            if uint(len(bytes)) != PointLength(curve, isCompressed) {
+                t.Error("The point was not serialized with a fixed length on " + nid)
+            }
+
+            point2, err := BytesToPoint(bytes, curve)
+            if err != nil {
+                t.Error(err)
+                continue
+            }
+
+            equal, err := point.Equals(point2)
+            if err != nil {
+                t.Error(err)
+            }
+            if !equal {
+                t.Error("The points were not equal on " + nid)
+            }
+            point2.Free()
+        }
+    }
+}
+
+func TestBytesToPointNonCanonical(t *testing.T) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Error(err)
+    }
+    defer curve.Free()
+
+    // x = 1 is a valid x coordinate on secp256k1, so x = p + 1 also
+    // fits in 32 bytes and is reduced to 1 by OpenSSL.
+    p, ok := new(big.Int).SetString(
+        "fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f", 16)
+    if !ok {
+        t.Error("Invalid field prime")
+    }
+
+    canonical := make([]byte, 33)
+    canonical[0] = 2
+    canonical[32] = 1
+
+    point, err := BytesToPoint(canonical, curve)
+    if err != nil {
+        t.Error(err)
+    }
+    defer point.Free()
+
+    nonCanonical := make([]byte, 33)
+    nonCanonical[0] = 2
+    copy(nonCanonical[1:], new(big.Int).Add(p, big.NewInt(1)).Bytes())
+
+    _, err = BytesToPoint(nonCanonical, curve)
+    if err == nil {
+        t.Error("A non-canonical point was deserialized")
+    }
+
+    _, err = BytesToPoint(canonical[:32], curve)
+    if err == nil {
+        t.Error("A short point was deserialized")
+    }
+}
+
 func TestPointMul(t *testing.T) {
     curve, err := openssl.NewCurve(openssl.SECP256K1)
     if err != nil {
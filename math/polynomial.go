@@ -0,0 +1,201 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package math
+
+import (
+    "errors"
+    "github.com/nucypher/goUmbral/openssl"
+)
+
+// Represents a polynomial over the integers modulo the order of a curve,
+// with its coefficients ordered from the lowest degree to the highest.
+
+type Polynomial struct {
+    Coeffs []*ModBigNum
+}
+
+// Returns a random Polynomial of the given degree with a copy of
+// constant as its constant term, as used for Shamir's secret sharing.
+//
+// The Polynomial must be freed by the calling function.
+func GenRandPolynomial(constant *ModBigNum, degree int) (*Polynomial, error) {
+    if degree < 0 {
+        return nil, errors.New("The degree of a polynomial cannot be negative")
+    }
+
+    poly := &Polynomial{Coeffs: make([]*ModBigNum, 0, degree + 1)}
+
+    coeff, err := constant.Copy()
+    if err != nil {
+        return nil, err
+    }
+    poly.Coeffs = append(poly.Coeffs, coeff)
+
+    // The coefficients are never zero, so the degree is exact.
+    for i := 0; i < degree; i++ {
+        coeff, err = GenRandModBN(constant.Curve)
+        if err != nil {
+            poly.Free()
+            return nil, err
+        }
+        poly.Coeffs = append(poly.Coeffs, coeff)
+    }
+    return poly, nil
+}
+
+// Returns the degree of the Polynomial.
+func (m *Polynomial) Degree() int {
+    return len(m.Coeffs) - 1
+}
+
+// Polynomial.Eval() evaluates the Polynomial at x with Horner's method.
+//
+// The result must be freed by the calling function.
+func (m *Polynomial) Eval(x *ModBigNum) (*ModBigNum, error) {
+    if len(m.Coeffs) == 0 {
+        return nil, errors.New("The polynomial has no coefficients")
+    }
+
+    result, err := m.Coeffs[len(m.Coeffs) - 1].Copy()
+    if err != nil {
+        return nil, err
+    }
+
+    for i := len(m.Coeffs) - 2; i >= 0; i-- {
+        err = result.Mul(result, x)
+        if err != nil {
+            result.Free()
+            return nil, err
+        }
+
+        err = result.Add(result, m.Coeffs[i])
+        if err != nil {
+            result.Free()
+            return nil, err
+        }
+    }
+    return result, nil
+}
+
+func (m *Polynomial) Copy() (*Polynomial, error) {
+    poly := &Polynomial{Coeffs: make([]*ModBigNum, 0, len(m.Coeffs))}
+    for _, coeff := range m.Coeffs {
+        coeffCopy, err := coeff.Copy()
+        if err != nil {
+            poly.Free()
+            return nil, err
+        }
+        poly.Coeffs = append(poly.Coeffs, coeffCopy)
+    }
+    return poly, nil
+}
+
+func (m *Polynomial) Free() {
+    freeModBNs(m.Coeffs)
+}
+
+// Returns the Lagrange coefficients at zero of the shares at xs,
+// so that f(0) is the sum of lambda_i * f(xs[i]) for any Polynomial f
+// of degree below len(xs).
+//
+// The xs must be distinct, and a single inversion is made for all of them.
+// The coefficients must be freed by the calling function.
+func LagrangeCoeffsAtZero(xs []*ModBigNum) ([]*ModBigNum, error) {
+    if len(xs) == 0 {
+        return nil, errors.New("There must be at least one share")
+    }
+    curve := xs[0].Curve
+
+    // lambda_i = prod(xj) / prod(xj - xi) for all j != i
+    nums := make([]*ModBigNum, len(xs))
+    dens := make([]*ModBigNum, len(xs))
+    defer freeModBNs(nums)
+    defer freeModBNs(dens)
+
+    diff := newModBigNum(openssl.NewBigNum(), curve)
+    defer diff.Free()
+
+    var err error
+    for i, xi := range xs {
+        nums[i], err = IntToModBN(1, curve)
+        if err != nil {
+            return nil, err
+        }
+
+        dens[i], err = IntToModBN(1, curve)
+        if err != nil {
+            return nil, err
+        }
+
+        for j, xj := range xs {
+            if j == i {
+                continue
+            }
+            if xj.Equals(xi) {
+                return nil, errors.New("The x coordinates of the shares must be distinct")
+            }
+
+            err = diff.Sub(xj, xi)
+            if err != nil {
+                return nil, err
+            }
+
+            err = nums[i].Mul(nums[i], xj)
+            if err != nil {
+                return nil, err
+            }
+
+            err = dens[i].Mul(dens[i], diff)
+            if err != nil {
+                return nil, err
+            }
+        }
+    }
+
+    lambdas, err := BatchInvert(dens)
+    if err != nil {
+        return nil, err
+    }
+
+    for i := range lambdas {
+        err = lambdas[i].Mul(lambdas[i], nums[i])
+        if err != nil {
+            freeModBNs(lambdas)
+            return nil, err
+        }
+    }
+    return lambdas, nil
+}
+
+// Returns f(0) * P from the shares f(xs[i]) * P, by interpolating
+// in the exponent: the sum of lambda_i * points[i] where lambda_i are
+// the Lagrange coefficients at zero.
+//
+// The Point must be freed by the calling function.
+func InterpolateInExponent(xs []*ModBigNum, points []*Point) (*Point, error) {
+    if len(xs) != len(points) {
+        return nil, errors.New("There must be as many x coordinates as points")
+    }
+
+    lambdas, err := LagrangeCoeffsAtZero(xs)
+    if err != nil {
+        return nil, err
+    }
+    defer freeModBNs(lambdas)
+
+    return MultiScalarMul(points, lambdas)
+}
@@ -0,0 +1,193 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package math_test
+
+import (
+    "testing"
+    "github.com/nucypher/goUmbral/math"
+    "github.com/nucypher/goUmbral/openssl"
+)
+
+func TestPolynomialEval(t *testing.T) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer curve.Free()
+
+    // f(x) = 1 + 2x + 3x^2
+    poly := &math.Polynomial{}
+    defer poly.Free()
+    for _, c := range []int{1, 2, 3} {
+        coeff, err := math.IntToModBN(c, curve)
+        if err != nil {
+            t.Fatal(err)
+        }
+        poly.Coeffs = append(poly.Coeffs, coeff)
+    }
+
+    if poly.Degree() != 2 {
+        t.Error("The degree was", poly.Degree(), "instead of 2")
+    }
+
+    x, err := math.IntToModBN(5, curve)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer x.Free()
+
+    y, err := poly.Eval(x)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer y.Free()
+
+    expected, err := math.IntToModBN(86, curve)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer expected.Free()
+
+    if !y.Equals(expected) {
+        t.Error("f(5) was not equal to 86")
+    }
+}
+
+func TestLagrangeCoeffsAtZero(t *testing.T) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer curve.Free()
+
+    secret, err := math.GenRandModBN(curve)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer secret.Free()
+
+    threshold := 4
+    poly, err := math.GenRandPolynomial(secret, threshold - 1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer poly.Free()
+
+    if poly.Degree() != threshold - 1 || !poly.Coeffs[0].Equals(secret) {
+        t.Error("The random polynomial does not have the right degree or constant")
+    }
+
+    g := math.GetGeneratorFromCurve(curve)
+
+    xs := make([]*math.ModBigNum, threshold)
+    points := make([]*math.Point, threshold)
+    for i := range xs {
+        xs[i], err = math.GenRandModBN(curve)
+        if err != nil {
+            t.Fatal(err)
+        }
+        defer xs[i].Free()
+
+        y, err := poly.Eval(xs[i])
+        if err != nil {
+            t.Fatal(err)
+        }
+        defer y.Free()
+
+        points[i], err = g.Copy()
+        if err != nil {
+            t.Fatal(err)
+        }
+        defer points[i].Free()
+
+        err = points[i].Mul(g, y)
+        if err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    // The shares interpolate to the secret in the exponent.
+    result, err := math.InterpolateInExponent(xs, points)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer result.Free()
+
+    expected, err := g.Copy()
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer expected.Free()
+
+    err = expected.Mul(g, secret)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    equal, err := result.Equals(expected)
+    if err != nil {
+        t.Error(err)
+    }
+    if !equal {
+        t.Error("The shares did not interpolate to the secret in the exponent")
+    }
+
+    // The shares interpolate to the secret.
+    lambdas, err := math.LagrangeCoeffsAtZero(xs)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    sum, err := math.IntToModBN(1, curve)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer sum.Free()
+
+    err = sum.Sub(sum, sum)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    for i, lambda := range lambdas {
+        y, err := poly.Eval(xs[i])
+        if err != nil {
+            t.Fatal(err)
+        }
+        defer y.Free()
+
+        err = y.Mul(y, lambda)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        err = sum.Add(sum, y)
+        if err != nil {
+            t.Fatal(err)
+        }
+        lambda.Free()
+    }
+
+    if !sum.Equals(secret) {
+        t.Error("The shares did not interpolate to the secret")
+    }
+
+    _, err = math.LagrangeCoeffsAtZero([]*math.ModBigNum{xs[0], xs[1], xs[0]})
+    if err == nil {
+        t.Error("The coefficients of duplicate shares were computed")
+    }
+}
@@ -0,0 +1,260 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package math
+
+import (
+    "errors"
+    "math/big"
+    "sync"
+    "github.com/nucypher/goUmbral/openssl"
+)
+
+// Represents the arithmetic of a curve y^2 = x^3 + a*x + b on Points
+// in projective coordinates, using the complete addition formulas of
+// Renes, Costello and Batina, which hold for the curves of prime order.
+//
+// Every operation runs in constant time with respect to the Points.
+
+type curveArithmetic struct {
+    field *primeField
+    // The coefficient a and 3 * b of the curve.
+    a, b3 fieldElement
+}
+
+// Represents a Point (X : Y : Z) of the curve with x = X / Z and y = Y / Z.
+// The point at infinity is (0 : 1 : 0).
+
+type projectivePoint struct {
+    x, y, z fieldElement
+}
+
+var curveArithmetics = struct {
+    sync.Mutex
+    byNID map[int]*curveArithmetic
+}{byNID: make(map[int]*curveArithmetic)}
+
+// Returns the curveArithmetic of the curve, building it on first use.
+func getCurveArithmetic(curve *openssl.Curve) (*curveArithmetic, error) {
+    curveArithmetics.Lock()
+    defer curveArithmetics.Unlock()
+
+    arith, ok := curveArithmetics.byNID[curve.NID]
+    if ok {
+        return arith, nil
+    }
+
+    arith, err := newCurveArithmetic(curve)
+    if err != nil {
+        return nil, err
+    }
+    curveArithmetics.byNID[curve.NID] = arith
+    return arith, nil
+}
+
+// All the curves supported by openssl.NewCurve have a prime order,
+// so the complete formulas hold for them.
+func newCurveArithmetic(curve *openssl.Curve) (*curveArithmetic, error) {
+    pBN, aBN, bBN, err := openssl.GetECGroupCurve(curve.Group)
+    if err != nil {
+        return nil, err
+    }
+    defer openssl.FreeBigNum(pBN)
+    defer openssl.FreeBigNum(aBN)
+    defer openssl.FreeBigNum(bBN)
+
+    p, err := openssl.BNToBigInt(pBN)
+    if err != nil {
+        return nil, err
+    }
+
+    a, err := openssl.BNToBigInt(aBN)
+    if err != nil {
+        return nil, err
+    }
+
+    b, err := openssl.BNToBigInt(bBN)
+    if err != nil {
+        return nil, err
+    }
+
+    f := newPrimeField(p)
+    return &curveArithmetic{field: f, a: f.fromBig(a),
+        b3: f.fromBig(new(big.Int).Mul(b, big.NewInt(3)))}, nil
+}
+
+// Returns the point at infinity.
+func (m *curveArithmetic) infinity() projectivePoint {
+    f := m.field
+    return projectivePoint{f.newElement(), append(fieldElement(nil), f.one...), f.newElement()}
+}
+
+// Returns the projective Point of the affine coordinates x and y.
+func (m *curveArithmetic) fromAffine(x, y fieldElement) projectivePoint {
+    f := m.field
+    return projectivePoint{x, y, append(fieldElement(nil), f.one...)}
+}
+
+// Returns the projective Point of the Point.
+func (m *curveArithmetic) fromPoint(point *Point) (projectivePoint, error) {
+    x, y, err := point.ToAffine()
+    if err != nil {
+        return projectivePoint{}, err
+    }
+    return m.fromAffine(m.field.fromBig(x), m.field.fromBig(y)), nil
+}
+
+// Returns the affine coordinates of the projective Point,
+// or an error if it is the point at infinity.
+func (m *curveArithmetic) toAffine(p projectivePoint) (fieldElement, fieldElement, error) {
+    f := m.field
+    if f.isZero(p.z) == 1 {
+        return nil, nil, errors.New("The point at infinity has no affine coordinates")
+    }
+
+    zInv := f.newElement()
+    f.inv(zInv, p.z)
+
+    x := f.newElement()
+    y := f.newElement()
+    f.mul(x, p.x, zInv)
+    f.mul(y, p.y, zInv)
+    return x, y, nil
+}
+
+// Returns the Point of the projective Point.
+func (m *curveArithmetic) toPoint(p projectivePoint, curve *openssl.Curve) (*Point, error) {
+    x, y, err := m.toAffine(p)
+    if err != nil {
+        return nil, err
+    }
+
+    // The coordinates are passed to OpenSSL as fixed-width bytes,
+    // since converting them to BIGNUMs through big.Int is not constant time.
+    f := m.field
+    data := make([]byte, 1 + 2 * f.size)
+    defer func() {
+        for i := range data {
+            data[i] = 0
+        }
+    }()
+    data[0] = 4
+    f.putBytes(data[1:1 + f.size], f.fromMont(x))
+    f.putBytes(data[1 + f.size:], f.fromMont(y))
+
+    point, err := openssl.NewECPoint(curve)
+    if err != nil {
+        return nil, err
+    }
+
+    ctx := openssl.GetBNCtx()
+    defer openssl.PutBNCtx(ctx)
+
+    err = openssl.OctToECP(curve.Group, point, data, ctx)
+    if err != nil {
+        openssl.FreeECPoint(point)
+        return nil, err
+    }
+    return newPoint(point, curve), nil
+}
+
+// Sets z to p if c is 1, and leaves it unchanged if c is 0.
+func (m *curveArithmetic) cmov(z *projectivePoint, p projectivePoint, c uint64) {
+    f := m.field
+    f.cmov(z.x, z.x, p.x, c)
+    f.cmov(z.y, z.y, p.y, c)
+    f.cmov(z.z, z.z, p.z, c)
+}
+
+// Holds the temporaries of curveArithmetic.addTo, so that a loop of
+// additions does not allocate them on the heap for every step.
+
+type addScratch struct {
+    limbs [9][maxFieldLimbs]uint64
+}
+
+// Returns p + q, using the complete projective addition of Renes,
+// Costello and Batina (algorithm 1).
+func (m *curveArithmetic) add(p, q projectivePoint) projectivePoint {
+    f := m.field
+    z := projectivePoint{f.newElement(), f.newElement(), f.newElement()}
+
+    var scratch addScratch
+    m.addTo(&z, p, q, &scratch)
+    return z
+}
+
+// Sets z to p + q, which z may alias.
+func (m *curveArithmetic) addTo(z *projectivePoint, p, q projectivePoint, scratch *addScratch) {
+    f := m.field
+    x1, y1, z1 := p.x, p.y, p.z
+    x2, y2, z2 := q.x, q.y, q.z
+
+    t0 := scratch.limbs[0][:f.n]
+    t1 := scratch.limbs[1][:f.n]
+    t2 := scratch.limbs[2][:f.n]
+    t3 := scratch.limbs[3][:f.n]
+    t4 := scratch.limbs[4][:f.n]
+    t5 := scratch.limbs[5][:f.n]
+    x3 := scratch.limbs[6][:f.n]
+    y3 := scratch.limbs[7][:f.n]
+    z3 := scratch.limbs[8][:f.n]
+
+    f.mul(t0, x1, x2)
+    f.mul(t1, y1, y2)
+    f.mul(t2, z1, z2)
+    f.add(t3, x1, y1)
+    f.add(t4, x2, y2)
+    f.mul(t3, t3, t4)
+    f.add(t4, t0, t1)
+    f.sub(t3, t3, t4)
+    f.add(t4, x1, z1)
+    f.add(t5, x2, z2)
+    f.mul(t4, t4, t5)
+    f.add(t5, t0, t2)
+    f.sub(t4, t4, t5)
+    f.add(t5, y1, z1)
+    f.add(x3, y2, z2)
+    f.mul(t5, t5, x3)
+    f.add(x3, t1, t2)
+    f.sub(t5, t5, x3)
+    f.mul(z3, m.a, t4)
+    f.mul(x3, m.b3, t2)
+    f.add(z3, x3, z3)
+    f.sub(x3, t1, z3)
+    f.add(z3, t1, z3)
+    f.mul(y3, x3, z3)
+    f.add(t1, t0, t0)
+    f.add(t1, t1, t0)
+    f.mul(t2, m.a, t2)
+    f.mul(t4, m.b3, t4)
+    f.add(t1, t1, t2)
+    f.sub(t2, t0, t2)
+    f.mul(t2, m.a, t2)
+    f.add(t4, t4, t2)
+    f.mul(t0, t1, t4)
+    f.add(y3, y3, t0)
+    f.mul(t0, t5, t4)
+    f.mul(x3, t3, x3)
+    f.sub(x3, x3, t0)
+    f.mul(t0, t3, t1)
+    f.mul(z3, t5, z3)
+    f.add(z3, z3, t0)
+
+    copy(z.x, x3)
+    copy(z.y, y3)
+    copy(z.z, z3)
+}
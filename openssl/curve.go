@@ -19,7 +19,8 @@ package openssl
 // #include "shim.h"
 import "C"
 import (
-    "errors"
+    "runtime"
+    "sync/atomic"
 )
 
 // Supported curves
@@ -29,11 +30,28 @@ const (
     SECP384R1 = C.NID_secp384r1
 )
 
+// Represents an elliptic curve: its EC_GROUP, order and generator.
+//
+// A Curve is safe for concurrent use by multiple goroutines, as long as
+// none of them modifies its fields. It is reference counted: NewCurve
+// returns a Curve with one reference, which is dropped by Free, and each
+// ModBigNum or Point built by the math package holds another one until
+// it is freed. The OpenSSL objects are only freed with the last reference,
+// so a Curve may be freed while Points still use it.
+
 type Curve struct {
     NID int
     Group ECGroup
     Order BigNum
     Generator ECPoint
+
+    // The Montgomery context of the order, shared by all exponentiations.
+    orderMontCtx BNMontCtx
+
+    // The number of references to the Curve, updated atomically.
+    refs int32
+    // Set once Free dropped the reference of NewCurve.
+    freed int32
 }
 
 func NewCurve(nid C.int) (*Curve, error) {
@@ -47,7 +65,7 @@ func NewCurve(nid C.int) (*Curve, error) {
     case SECP256K1:
     case SECP384R1:
     default:
-        return nil, errors.New("This curve is not supported. Please use one of the constant curves defined in curve.go.")
+        return nil, ErrUnsupportedCurve
     }
     group, err := GetECGroupByCurveNID(nid)
     if err != nil {
@@ -55,13 +73,31 @@ func NewCurve(nid C.int) (*Curve, error) {
     }
     order, err := GetECOrderByGroup(group)
     if err != nil {
+        FreeECGroup(group)
         return nil, err
     }
     generator, err := GetECGeneratorByGroup(group)
     if err != nil {
+        FreeBigNum(order)
+        FreeECGroup(group)
         return nil, err
     }
-    return &Curve{int(nid), group, order, generator}, nil
+    orderMontCtx, err := TmpBNMontCTX(order)
+    if err != nil {
+        FreeBigNum(order)
+        FreeECGroup(group)
+        return nil, err
+    }
+    curve := &Curve{
+        NID: int(nid),
+        Group: group,
+        Order: order,
+        Generator: generator,
+        orderMontCtx: orderMontCtx,
+        refs: 1,
+    }
+    runtime.SetFinalizer(curve, (*Curve).Free)
+    return curve, nil
 }
 
 func (m *Curve) Equals(other *Curve) bool {
@@ -73,9 +109,57 @@ func (m *Curve) FieldOrderSize() uint {
     return (bits + 7) / 8
 }
 
+// Returns the Montgomery context of the order of the Curve.
+//
+// It is owned by the Curve and must not be freed by the calling function.
+// It is only read by the exponentiations, so it may be shared between goroutines.
+func (m *Curve) OrderMontCtx() BNMontCtx {
+    return m.orderMontCtx
+}
+
+// Curve.Retain() adds a reference to the Curve, which must be dropped
+// with Release once the calling function no longer uses the Curve.
+func (m *Curve) Retain() *Curve {
+    if atomic.AddInt32(&m.refs, 1) <= 1 {
+        panic("Retain called on a freed curve")
+    }
+    return m
+}
+
+// Curve.Release() drops a reference taken with Retain,
+// and frees the Curve if it was the last one.
+func (m *Curve) Release() {
+    refs := atomic.AddInt32(&m.refs, -1)
+    if refs < 0 {
+        panic("Release called on a freed curve")
+    }
+    if refs == 0 {
+        m.free()
+    }
+}
+
+// Curve.Free() drops the reference returned by NewCurve.
+// The EC_GROUP, the order and its Montgomery context are freed
+// once the ModBigNums and Points of the Curve are freed as well.
+//
+// It is safe to call Free more than once, and on a nil Curve.
+// A Curve that is not freed is freed when it is garbage collected.
 func (m *Curve) Free() {
+    if m == nil || !atomic.CompareAndSwapInt32(&m.freed, 0, 1) {
+        return
+    }
+    runtime.SetFinalizer(m, nil)
+    m.Release()
+}
+
+func (m *Curve) free() {
+    FreeBNMontCtx(m.orderMontCtx)
     FreeBigNum(m.Order)
     FreeECGroup(m.Group)
     // The generator is already freed by freeing the EC_GROUP.
     // FreeECPoint(m.Generator)
+    m.Group = nil
+    m.Order = nil
+    m.Generator = nil
+    m.orderMontCtx = nil
 }
@@ -17,6 +17,7 @@
 package openssl
 
 import (
+    "sync"
     "testing"
 )
 
@@ -70,3 +71,32 @@ func TestFree(t *testing.T) {
         curve.Free()
     }
 }
+
+func TestCurveRetain(t *testing.T) {
+    curve, err := NewCurve(SECP256K1)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    var wg sync.WaitGroup
+    for i := 0; i < 200; i++ {
+        wg.Add(1)
+        go func(curve *Curve) {
+            defer wg.Done()
+            defer curve.Release()
+
+            if curve.FieldOrderSize() != 32 {
+                t.Error("Got:", curve.FieldOrderSize(), "Expected: 32")
+            }
+        }(curve.Retain())
+    }
+
+    // Free only drops the reference of NewCurve, once.
+    curve.Free()
+    curve.Free()
+    wg.Wait()
+
+    if curve.Group != nil {
+        t.Error("The curve should be freed with its last reference")
+    }
+}
@@ -0,0 +1,183 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+//
+// This file wraps the OpenSSL ECDSA functions that are relevant to Umbral.
+//
+// If there is an error then an OpenSSLError will be returned,
+// otherwise the functions will return a nil error.
+package openssl
+
+// #include "shim.h"
+import "C"
+import (
+    "bytes"
+    "unsafe"
+)
+
+// NewECKey returns an EC_KEY on the curve holding the private key
+// and the public key. Either of them may be nil.
+func NewECKey(curve *Curve, privKey BigNum, pubKey ECPoint) (ECKey, error) {
+    defer isolateErrors()()
+    // key must be freed later by the calling function.
+    var key ECKey = C.EC_KEY_new()
+    if key == nil {
+        return nil, NewOpenSSLError()
+    }
+
+    result := C.EC_KEY_set_group(key, curve.Group)
+    if result != 1 {
+        defer FreeECKey(key)
+        return nil, NewOpenSSLError()
+    }
+
+    if privKey != nil {
+        result = C.EC_KEY_set_private_key(key, privKey)
+        if result != 1 {
+            defer FreeECKey(key)
+            return nil, NewOpenSSLError()
+        }
+    }
+
+    if pubKey != nil {
+        result = C.EC_KEY_set_public_key(key, pubKey)
+        if result != 1 {
+            defer FreeECKey(key)
+            return nil, NewOpenSSLError()
+        }
+    }
+    return key, nil
+}
+
+func FreeECKey(key ECKey) {
+    C.EC_KEY_free(key)
+}
+
+// NewECDSASig wraps ECDSA_SIG_new and ECDSA_SIG_set0.
+//
+// The ECDSA_SIG takes ownership of r and s.
+func NewECDSASig(r, s BigNum) (ECDSASig, error) {
+    defer isolateErrors()()
+    // sig must be freed later by the calling function.
+    var sig ECDSASig = C.ECDSA_SIG_new()
+    if sig == nil {
+        return nil, NewOpenSSLError()
+    }
+
+    result := C.ECDSA_SIG_set0(sig, r, s)
+    if result != 1 {
+        defer FreeECDSASig(sig)
+        return nil, NewOpenSSLError()
+    }
+    // r and s are freed with the ECDSA_SIG from now on.
+    trackBigNums(-2)
+    return sig, nil
+}
+
+// GetECDSASigRS wraps ECDSA_SIG_get0.
+//
+// r and s should not be freed directly by the calling function.
+// Free the ECDSA_SIG instead.
+func GetECDSASigRS(sig ECDSASig) (BigNum, BigNum) {
+    var r, s *C.BIGNUM
+    C.ECDSA_SIG_get0(sig, &r, &s)
+    return r, s
+}
+
+func FreeECDSASig(sig ECDSASig) {
+    C.ECDSA_SIG_free(sig)
+}
+
+// ECDSASign wraps ECDSA_do_sign.
+//
+// The digest is truncated to the bit length of the order of the curve.
+func ECDSASign(digest []byte, key ECKey) (ECDSASig, error) {
+    defer isolateErrors()()
+    cDigest := C.CBytes(digest)
+    defer C.free(cDigest)
+
+    // sig must be freed later by the calling function.
+    var sig ECDSASig = C.ECDSA_do_sign((*C.uchar)(cDigest), C.int(len(digest)), key)
+    if sig == nil {
+        return nil, NewOpenSSLError()
+    }
+    return sig, nil
+}
+
+// ECDSAVerify wraps ECDSA_do_verify.
+//
+// It returns false with a nil error if the signature is incorrect.
+func ECDSAVerify(digest []byte, sig ECDSASig, key ECKey) (bool, error) {
+    defer isolateErrors()()
+    cDigest := C.CBytes(digest)
+    defer C.free(cDigest)
+
+    result := C.ECDSA_do_verify((*C.uchar)(cDigest), C.int(len(digest)), sig, key)
+    if result == -1 {
+        return false, NewOpenSSLError()
+    }
+    if result != 1 {
+        // An incorrect signature may leave an error in the queue.
+        C.ERR_clear_error()
+        return false, nil
+    }
+    return true, nil
+}
+
+// ECDSASigToDER wraps i2d_ECDSA_SIG.
+func ECDSASigToDER(sig ECDSASig) ([]byte, error) {
+    defer isolateErrors()()
+    size := C.i2d_ECDSA_SIG(sig, nil)
+    if size <= 0 {
+        return nil, NewOpenSSLError()
+    }
+
+    cSpace := C.malloc(C.size_t(size))
+    defer C.free(cSpace)
+
+    var cursor *C.uchar = (*C.uchar)(cSpace)
+    written := C.i2d_ECDSA_SIG(sig, &cursor)
+    if written != size {
+        return nil, NewOpenSSLError()
+    }
+    return C.GoBytes(cSpace, written), nil
+}
+
+// DERToECDSASig wraps d2i_ECDSA_SIG.
+//
+// Only the canonical DER encoding of an ECDSA_SIG is accepted.
+func DERToECDSASig(data []byte) (ECDSASig, error) {
+    if len(data) == 0 {
+        return nil, &EncodingError{"No bytes failure"}
+    }
+    cData := C.CBytes(data)
+    defer C.free(cData)
+
+    var cursor *C.uchar = (*C.uchar)(cData)
+    // sig must be freed later by the calling function.
+    var sig ECDSASig = C.d2i_ECDSA_SIG(nil, &cursor, C.long(len(data)))
+    if sig == nil {
+        return nil, &EncodingError{"The signature is not DER encoded"}
+    }
+
+    consumed := uintptr(unsafe.Pointer(cursor)) - uintptr(cData)
+    encoded, err := ECDSASigToDER(sig)
+    if err != nil || int(consumed) != len(data) || !bytes.Equal(encoded, data) {
+        FreeECDSASig(sig)
+        return nil, &EncodingError{"The signature is not canonically DER encoded"}
+    }
+    return sig, nil
+}
@@ -0,0 +1,140 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package openssl
+
+import (
+    "bytes"
+    "testing"
+)
+
+func TestECDSASignVerify(t *testing.T) {
+    curve, err := NewCurve(SECP256R1)
+    if err != nil {
+        t.Error(err)
+    }
+    defer curve.Free()
+
+    privKey := NewBigNum()
+    defer FreeBigNum(privKey)
+
+    err = RandRangeBN(privKey, curve.Order)
+    if err != nil {
+        t.Error(err)
+    }
+
+    pubKey, err := NewECPoint(curve)
+    if err != nil {
+        t.Error(err)
+    }
+    defer FreeECPoint(pubKey)
+
+    ctx := NewBNCtx()
+    defer FreeBNCtx(ctx)
+
+    err = MulECP(curve.Group, pubKey, privKey, nil, nil, ctx)
+    if err != nil {
+        t.Error(err)
+    }
+
+    signingKey, err := NewECKey(curve, privKey, pubKey)
+    if err != nil {
+        t.Error(err)
+    }
+    defer FreeECKey(signingKey)
+
+    verifyingKey, err := NewECKey(curve, nil, pubKey)
+    if err != nil {
+        t.Error(err)
+    }
+    defer FreeECKey(verifyingKey)
+
+    digest := bytes.Repeat([]byte{0x42}, 32)
+
+    sig, err := ECDSASign(digest, signingKey)
+    if err != nil {
+        t.Error(err)
+    }
+    defer FreeECDSASig(sig)
+
+    valid, err := ECDSAVerify(digest, sig, verifyingKey)
+    if err != nil {
+        t.Error(err)
+    }
+    if !valid {
+        t.Error("The signature did not verify")
+    }
+
+    digest[0] ^= 1
+    valid, err = ECDSAVerify(digest, sig, verifyingKey)
+    if err != nil {
+        t.Error(err)
+    }
+    if valid {
+        t.Error("The signature verified a different digest")
+    }
+}
+
+func TestECDSASigDER(t *testing.T) {
+    r, err := IntToBN(0x1234)
+    if err != nil {
+        t.Error(err)
+    }
+
+    s, err := IntToBN(0x80)
+    if err != nil {
+        t.Error(err)
+    }
+
+    sig, err := NewECDSASig(r, s)
+    if err != nil {
+        t.Error(err)
+    }
+    defer FreeECDSASig(sig)
+
+    der, err := ECDSASigToDER(sig)
+    if err != nil {
+        t.Error(err)
+    }
+
+    // SEQUENCE { INTEGER 0x1234, INTEGER 0x0080 }
+    expected := []byte{0x30, 0x08, 0x02, 0x02, 0x12, 0x34, 0x02, 0x02, 0x00, 0x80}
+    if !bytes.Equal(der, expected) {
+        t.Errorf("Unexpected DER encoding %x", der)
+    }
+
+    newSig, err := DERToECDSASig(der)
+    if err != nil {
+        t.Error(err)
+    }
+    defer FreeECDSASig(newSig)
+
+    newR, newS := GetECDSASigRS(newSig)
+    if CmpBN(newR, r) != 0 || CmpBN(newS, s) != 0 {
+        t.Error("The decoded signature does not match")
+    }
+
+    _, err = DERToECDSASig(append(der, 0))
+    if err == nil {
+        t.Error("A DER encoding with trailing bytes was accepted")
+    }
+
+    // The integer 0x80 must have a leading zero to be positive.
+    _, err = DERToECDSASig([]byte{0x30, 0x07, 0x02, 0x02, 0x12, 0x34, 0x02, 0x01, 0x80})
+    if err == nil {
+        t.Error("A negative integer was accepted")
+    }
+}
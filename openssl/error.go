@@ -19,32 +19,145 @@ package openssl
 // #include "shim.h"
 import "C"
 import (
+    "errors"
     "fmt"
+    "runtime"
 )
 
 const ERR_R_FATAL = 64
 
+// Errors that callers may check for with errors.Is.
+var (
+    // Returned for a curve that is not one of the constants of curve.go.
+    ErrUnsupportedCurve = errors.New("This curve is not supported. Please use one of the constant curves defined in curve.go.")
+
+    // Returned for a malformed serialization.
+    ErrInvalidEncoding = errors.New("Invalid encoding")
+
+    // Returned for coordinates that do not describe a point of the curve.
+    ErrPointNotOnCurve = errors.New("The point is not on the curve")
+
+    // Returned for the square root of a number that is not a square.
+    ErrNotASquare = errors.New("The number is not a square")
+)
+
+// Represents a malformed serialization, with the reason it was rejected.
+//
+// An EncodingError matches ErrInvalidEncoding with errors.Is.
+
+type EncodingError struct {
+    Reason string
+}
+
+func (m *EncodingError) Error() string {
+    return m.Reason
+}
+
+func (m *EncodingError) Is(target error) bool {
+    return target == ErrInvalidEncoding
+}
+
+// Represents an error from the OpenSSL error queue.
+//
+// Code is the packed error code of OpenSSL, and Library, Function and Reason
+// are its human readable parts. Function is empty with OpenSSL 3.
+// The numeric library and reason are given by LibraryCode and ReasonCode.
+//
+// OpenSSL queues an error for each function that fails on the way back
+// from the root cause. An OpenSSLError is the last of them, and Cause holds
+// the one queued before it, so that errors.Is and errors.As search the queue.
+
 type OpenSSLError struct {
-    code uint64
-    library string
-    function string
-    reason string
-    fatal bool
+    Code uint64
+    Library string
+    Function string
+    Reason string
+    Fatal bool
+    Cause *OpenSSLError
 }
 
 func (m *OpenSSLError) Error() string {
-    if m.fatal {
-        return fmt.Sprintf("OpenSSL FATAL Error: %x:%s:%s:%s",
-            m.code, m.library, m.function, m.reason)
+    var msg string
+    if m.Fatal {
+        msg = fmt.Sprintf("OpenSSL FATAL Error: %x:%s:%s:%s",
+            m.Code, m.Library, m.Function, m.Reason)
     } else {
-        return fmt.Sprintf("OpenSSL NON_FATAL Error: %x:%s:%s:%s",
-            m.code, m.library, m.function, m.reason)
+        msg = fmt.Sprintf("OpenSSL NON_FATAL Error: %x:%s:%s:%s",
+            m.Code, m.Library, m.Function, m.Reason)
+    }
+    if m.Cause != nil {
+        msg += ": " + m.Cause.Error()
+    }
+    return msg
+}
+
+// Returns the error queued before this one, or nil if it is the root cause.
+func (m *OpenSSLError) Unwrap() error {
+    if m.Cause == nil {
+        return nil
+    }
+    return m.Cause
+}
+
+// Returns the library of the error, such as ERR_LIB_EC.
+func (m *OpenSSLError) LibraryCode() int {
+    return int(C.shim_err_get_lib(C.ulong(m.Code)))
+}
+
+// Returns the reason of the error within its library,
+// such as EC_R_POINT_IS_NOT_ON_CURVE.
+func (m *OpenSSLError) ReasonCode() int {
+    return int(C.shim_err_get_reason(C.ulong(m.Code)))
+}
+
+// OpenSSLError.Is() lets errors.Is match the errors of OpenSSL caused
+// by bad input against ErrPointNotOnCurve, ErrInvalidEncoding and ErrNotASquare.
+func (m *OpenSSLError) Is(target error) bool {
+    lib := m.LibraryCode()
+    reason := m.ReasonCode()
+
+    switch target {
+    case ErrPointNotOnCurve:
+        // An invalid compressed point has an x coordinate without any y.
+        return lib == C.ERR_LIB_EC && (reason == C.EC_R_POINT_IS_NOT_ON_CURVE ||
+            reason == C.EC_R_INVALID_COMPRESSED_POINT)
+    case ErrInvalidEncoding:
+        if lib == C.ERR_LIB_ASN1 {
+            return true
+        }
+        return lib == C.ERR_LIB_EC && (reason == C.EC_R_INVALID_ENCODING ||
+            reason == C.EC_R_INVALID_COMPRESSION_BIT ||
+            reason == C.EC_R_BUFFER_TOO_SMALL)
+    case ErrNotASquare:
+        return lib == C.ERR_LIB_BN && reason == C.BN_R_NOT_A_SQUARE
     }
+    return false
 }
 
+// NewOpenSSLError() empties the OpenSSL error queue of the current thread
+// into a chain of OpenSSLErrors, starting from the last one queued.
+//
+// The queue is per thread, so it must be called by the function that made
+// the failing calls, after isolateErrors.
+// If the queue is empty, the OpenSSLError has a zero Code.
 func NewOpenSSLError() *OpenSSLError {
-    var code C.ulong = C.ERR_get_error()
+    var last *OpenSSLError
+    for {
+        var code C.ulong = C.ERR_get_error()
+        if code == 0 {
+            break
+        }
+        err := openSSLErrorFromCode(code)
+        err.Cause = last
+        last = err
+    }
+    if last == nil {
+        return openSSLErrorFromCode(0)
+    }
+    return last
+}
 
+func openSSLErrorFromCode(code C.ulong) *OpenSSLError {
     var library *C.char = C.ERR_lib_error_string(code)
     var function *C.char = C.ERR_func_error_string(code)
     var reason *C.char = C.ERR_reason_error_string(code)
@@ -55,5 +168,28 @@ func NewOpenSSLError() *OpenSSLError {
 
     fatal := code & ERR_R_FATAL
 
-    return &OpenSSLError{uint64(code), goLib, goFun, goRea, fatal != 0}
+    return &OpenSSLError{
+        Code: uint64(code),
+        Library: goLib,
+        Function: goFun,
+        Reason: goRea,
+        Fatal: fatal != 0,
+    }
+}
+
+// isolateErrors() pins the goroutine to its OS thread and clears
+// the OpenSSL error queue of that thread, so that the queue only holds
+// the errors of the calls that follow. The returned function unpins
+// the goroutine, and must be deferred:
+//
+//     defer isolateErrors()()
+//
+// The wrappers that may call NewOpenSSLError start with it.
+func isolateErrors() func() {
+    runtime.LockOSThread()
+    // Peeking is cheaper than clearing an empty queue.
+    if C.ERR_peek_error() != 0 {
+        C.ERR_clear_error()
+    }
+    return runtime.UnlockOSThread
 }
@@ -17,6 +17,8 @@
 package openssl
 
 import (
+    "errors"
+    "sync"
     "testing"
 )
 
@@ -43,4 +45,99 @@ func TestError(t *testing.T) {
     if err == nil {
         t.Error("Should have returned error: 'OpenSSL FATAL Error: 307a073:bignum routines:BN_rand_range:invalid range'")
     }
+
+    var sslErr *OpenSSLError
+    if !errors.As(err, &sslErr) {
+        t.Fatal("Got:", err, "Expected an *OpenSSLError")
+    }
+    if sslErr.Code == 0 || sslErr.LibraryCode() == 0 || sslErr.ReasonCode() == 0 {
+        t.Error("The code of the error is not set:", sslErr)
+    }
+    if sslErr.Library == "" || sslErr.Reason == "" {
+        t.Error("The library and reason of the error are not set:", sslErr)
+    }
+    if errors.Is(err, ErrInvalidEncoding) || errors.Is(err, ErrPointNotOnCurve) {
+        t.Error("An invalid range should not match the input errors:", sslErr)
+    }
+}
+
+func TestEncodingError(t *testing.T) {
+    _, err := DERToECDSASig(nil)
+    if !errors.Is(err, ErrInvalidEncoding) {
+        t.Error("Got:", err, "Expected:", ErrInvalidEncoding)
+    }
+
+    // A DER sequence holding a single integer.
+    _, err = DERToECDSASig([]byte{0x30, 0x03, 0x02, 0x01, 0x01})
+    if !errors.Is(err, ErrInvalidEncoding) {
+        t.Error("Got:", err, "Expected:", ErrInvalidEncoding)
+    }
+}
+
+// Returns the error of BN_rand_range with a negative range.
+func invalidRangeError() error {
+    five, err := IntToBN(5)
+    if err != nil {
+        return err
+    }
+    defer FreeBigNum(five)
+
+    max := NewBigNum()
+    defer FreeBigNum(max)
+
+    err = SubBN(max, max, five)
+    if err != nil {
+        return err
+    }
+
+    r := NewBigNum()
+    defer FreeBigNum(r)
+
+    return RandRangeBN(r, max)
+}
+
+func TestErrorQueueIsolation(t *testing.T) {
+    expected, ok := invalidRangeError().(*OpenSSLError)
+    if !ok || expected.Code == 0 {
+        t.Fatal("Got:", expected, "Expected an OpenSSL error")
+    }
+
+    // The goroutines move between threads and fail concurrently,
+    // but each of them must only see its own error.
+    var wg sync.WaitGroup
+    errs := make(chan error, 16 * 100)
+    for i := 0; i < 16; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for j := 0; j < 100; j++ {
+                errs <- invalidRangeError()
+            }
+        }()
+    }
+    wg.Wait()
+    close(errs)
+
+    for err := range errs {
+        sslErr, ok := err.(*OpenSSLError)
+        if !ok || sslErr.Code != expected.Code || sslErr.Cause != nil {
+            t.Fatal("Got:", err, "Expected:", expected)
+        }
+    }
+}
+
+func TestErrorChain(t *testing.T) {
+    root := &OpenSSLError{Code: 1, Reason: "root"}
+    last := &OpenSSLError{Code: 2, Reason: "last", Cause: root}
+
+    var sslErr *OpenSSLError
+    if !errors.As(last, &sslErr) || sslErr != last {
+        t.Error("errors.As should return the last error of the queue")
+    }
+    if errors.Unwrap(last) != root || errors.Unwrap(root) != nil {
+        t.Error("Unwrap should go from the last error to the root cause")
+    }
+    if !errors.Is(last, root) {
+        t.Error("errors.Is should search the whole queue")
+    }
 }
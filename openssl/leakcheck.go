@@ -0,0 +1,51 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+
+// +build leakcheck
+
+package openssl
+
+import (
+    "sync/atomic"
+)
+
+// LeakCheck is true when the package is built with the leakcheck tag,
+// which counts the BIGNUMs and EC_POINTs that are allocated and freed
+// through this package, so that tests can check that none is leaked:
+//
+//     go test -tags leakcheck ./...
+const LeakCheck = true
+
+var liveBigNums, liveECPoints int64
+
+func trackBigNums(delta int64) {
+    atomic.AddInt64(&liveBigNums, delta)
+}
+
+func trackECPoints(delta int64) {
+    atomic.AddInt64(&liveECPoints, delta)
+}
+
+// LiveBigNums returns the number of BIGNUMs allocated and not yet freed.
+func LiveBigNums() int64 {
+    return atomic.LoadInt64(&liveBigNums)
+}
+
+// LiveECPoints returns the number of EC_POINTs allocated and not yet freed.
+func LiveECPoints() int64 {
+    return atomic.LoadInt64(&liveECPoints)
+}
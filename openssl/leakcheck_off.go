@@ -0,0 +1,40 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+
+// +build !leakcheck
+
+package openssl
+
+// LeakCheck is false unless the package is built with the leakcheck tag,
+// and no allocations are counted.
+const LeakCheck = false
+
+func trackBigNums(delta int64) {
+}
+
+func trackECPoints(delta int64) {
+}
+
+// LiveBigNums returns 0, since allocations are not counted.
+func LiveBigNums() int64 {
+    return 0
+}
+
+// LiveECPoints returns 0, since allocations are not counted.
+func LiveECPoints() int64 {
+    return 0
+}
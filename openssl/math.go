@@ -25,6 +25,10 @@ package openssl
 
 // #include "shim.h"
 import "C"
+import (
+    "errors"
+    "unsafe"
+)
 
 // SizeOfBN wraps BN_num_bytes.
 func SizeOfBN(bn BigNum) int {
@@ -39,6 +43,7 @@ func CmpBN(a, b BigNum) int {
 
 // AddBN wraps BN_add.
 func AddBN(r, a, b BigNum) error {
+    defer isolateErrors()()
     result := C.BN_add(r, a, b)
     if result != 1 {
         return NewOpenSSLError()
@@ -48,6 +53,7 @@ func AddBN(r, a, b BigNum) error {
 
 // SubBN wraps BN_sub.
 func SubBN(r, a, b BigNum) error {
+    defer isolateErrors()()
     result := C.BN_sub(r, a, b)
     if result != 1 {
         return NewOpenSSLError()
@@ -57,6 +63,7 @@ func SubBN(r, a, b BigNum) error {
 
 // MulBN wraps BN_mul.
 func MulBN(r, a, b BigNum, ctx BNCtx) error {
+    defer isolateErrors()()
     result := C.BN_mul(r, a, b, ctx)
     if result != 1 {
         return NewOpenSSLError()
@@ -66,6 +73,7 @@ func MulBN(r, a, b BigNum, ctx BNCtx) error {
 
 // DivBN wraps BN_div.
 func DivBN(dv, rem, a, d BigNum, ctx BNCtx) error {
+    defer isolateErrors()()
     result := C.BN_div(dv, rem, a, d, ctx)
     if result != 1 {
         return NewOpenSSLError()
@@ -85,6 +93,7 @@ func ModBN(rem, a, d BigNum, ctx BNCtx) error {
 
 // ModAddBN wraps BN_mod_add.
 func ModAddBN(r, a, b, m BigNum, ctx BNCtx) error {
+    defer isolateErrors()()
     result := C.BN_mod_add(r, a, b, m, ctx)
     if result != 1 {
         return NewOpenSSLError()
@@ -94,6 +103,7 @@ func ModAddBN(r, a, b, m BigNum, ctx BNCtx) error {
 
 // ModSubBN wraps BN_mod_sub.
 func ModSubBN(r, a, b, m BigNum, ctx BNCtx) error {
+    defer isolateErrors()()
     result := C.BN_mod_sub(r, a, b, m, ctx)
     if result != 1 {
         return NewOpenSSLError()
@@ -101,13 +111,20 @@ func ModSubBN(r, a, b, m BigNum, ctx BNCtx) error {
     return nil
 }
 
-// ModExpMontBN wraps BN_mod_exp_mont.
-func ModExpMontBN(r, a, b, m BigNum, ctx BNCtx) error {
-    montCtx, err := TmpBNMontCTX(m)
-    if err != nil {
-        return err
+// ModExpMontBN wraps BN_mod_exp_mont_consttime.
+//
+// montCtx must hold the Montgomery context of m, such as the one returned
+// by Curve.OrderMontCtx(). If montCtx is nil a temporary one is built.
+func ModExpMontBN(r, a, b, m BigNum, ctx BNCtx, montCtx BNMontCtx) error {
+    defer isolateErrors()()
+    if montCtx == nil {
+        tmpCtx, err := TmpBNMontCTX(m)
+        if err != nil {
+            return err
+        }
+        defer FreeBNMontCtx(tmpCtx)
+        montCtx = tmpCtx
     }
-    defer FreeBNMontCtx(montCtx)
 
     result := C.BN_mod_exp_mont_consttime(r, a, b, m, ctx, montCtx)
     if result != 1 {
@@ -118,6 +135,7 @@ func ModExpMontBN(r, a, b, m BigNum, ctx BNCtx) error {
 
 // ModMulBN wraps BN_mod_mul.
 func ModMulBN(r, a, b, m BigNum, ctx BNCtx) error {
+    defer isolateErrors()()
     result := C.BN_mod_mul(r, a, b, m, ctx)
     if result != 1 {
         return NewOpenSSLError()
@@ -127,6 +145,7 @@ func ModMulBN(r, a, b, m BigNum, ctx BNCtx) error {
 
 // ModInvertBN wraps BN_mod_inverse.
 func ModInvertBN(r, a, n BigNum, ctx BNCtx) error {
+    defer isolateErrors()()
     result := C.BN_mod_inverse(r, a, n, ctx)
     if result == nil {
         return NewOpenSSLError()
@@ -136,13 +155,9 @@ func ModInvertBN(r, a, n BigNum, ctx BNCtx) error {
 
 // ModNegBN negates 'a' and places the result in 'r'.
 func ModNegBN(r, a, m BigNum, ctx BNCtx) error {
-    zero, err := IntToBN(0)
-    if err != nil {
-        return err
-    }
-    defer FreeBigNum(zero)
-
-    result := C.BN_mod_sub(r, zero, a, m, ctx)
+    defer isolateErrors()()
+    // m - a is congruent to -a, and is reduced by BN_mod_sub.
+    result := C.BN_mod_sub(r, m, a, m, ctx)
     if result != 1 {
         return NewOpenSSLError()
     }
@@ -151,6 +166,7 @@ func ModNegBN(r, a, m BigNum, ctx BNCtx) error {
 
 // ModModBN wraps BN_nnmod.
 func ModModBN(r, a, m BigNum, ctx BNCtx) error {
+    defer isolateErrors()()
     result := C.BN_nnmod(r, a, m, ctx)
     if result != 1 {
         return NewOpenSSLError()
@@ -158,9 +174,34 @@ func ModModBN(r, a, m BigNum, ctx BNCtx) error {
     return nil
 }
 
+// ModSqrtBN wraps BN_mod_sqrt and places a square root of 'a' modulo
+// the prime 'p' in 'r'.
+//
+// It returns an error if 'a' is not a square modulo 'p'.
+// It does not run in constant time.
+func ModSqrtBN(r, a, p BigNum, ctx BNCtx) error {
+    defer isolateErrors()()
+    result := C.BN_mod_sqrt(r, a, p, ctx)
+    if result == nil {
+        return NewOpenSSLError()
+    }
+    return nil
+}
+
+// IsZeroBN wraps BN_is_zero.
+func IsZeroBN(a BigNum) bool {
+    return C.BN_is_zero(a) == 1
+}
+
+// IsOneBN wraps BN_is_one.
+func IsOneBN(a BigNum) bool {
+    return C.BN_is_one(a) == 1
+}
+
 // RandRangeBN wraps BN_rand_range and places a cryptographically
 // strong pseudo-random number in 'r' in the range 0 <= 'r' < 'max'.
 func RandRangeBN(r, max BigNum) error {
+    defer isolateErrors()()
     result := C.BN_rand_range(r, max)
     if result != 1 {
         return NewOpenSSLError()
@@ -170,14 +211,17 @@ func RandRangeBN(r, max BigNum) error {
 
 // DupBN wraps BN_dup.
 func DupBN(from BigNum) (BigNum, error) {
+    defer isolateErrors()()
     var bn BigNum = C.BN_dup(from)
     if bn == nil {
         return nil, NewOpenSSLError()
     }
+    trackBigNums(1)
     return bn, nil
 }
 
 func CmpECP(group ECGroup, a, b ECPoint, ctx BNCtx) (bool, error) {
+    defer isolateErrors()()
     result := C.EC_POINT_cmp(group, a, b, ctx)
     if result == -1 {
         return false, NewOpenSSLError()
@@ -186,6 +230,7 @@ func CmpECP(group ECGroup, a, b ECPoint, ctx BNCtx) (bool, error) {
 }
 
 func AddECP(group ECGroup, r, a, b ECPoint, ctx BNCtx) error {
+    defer isolateErrors()()
     result := C.EC_POINT_add(group, r, a, b, ctx)
     if result != 1 {
         return NewOpenSSLError()
@@ -194,6 +239,7 @@ func AddECP(group ECGroup, r, a, b ECPoint, ctx BNCtx) error {
 }
 
 func MulECP(group ECGroup, r ECPoint, n BigNum, q ECPoint, m BigNum, ctx BNCtx) error {
+    defer isolateErrors()()
     result := C.EC_POINT_mul(group, r, n, q, m, ctx)
     if result != 1 {
         return NewOpenSSLError()
@@ -201,7 +247,33 @@ func MulECP(group ECGroup, r ECPoint, n BigNum, q ECPoint, m BigNum, ctx BNCtx)
     return nil
 }
 
+// MulsECP wraps EC_POINTs_mul.
+//
+// It sets r = n * G + points[0] * scalars[0] + ... where G is the generator
+// of the group. n may be nil.
+func MulsECP(group ECGroup, r ECPoint, n BigNum, points []ECPoint, scalars []BigNum, ctx BNCtx) error {
+    defer isolateErrors()()
+    if len(points) != len(scalars) {
+        return errors.New("There must be as many scalars as points")
+    }
+
+    var cPoints **C.EC_POINT
+    var cScalars **C.BIGNUM
+    if len(points) > 0 {
+        // The slices only hold C pointers, so they can be passed to C.
+        cPoints = (**C.EC_POINT)(unsafe.Pointer(&points[0]))
+        cScalars = (**C.BIGNUM)(unsafe.Pointer(&scalars[0]))
+    }
+
+    result := C.EC_POINTs_mul(group, r, n, C.size_t(len(points)), cPoints, cScalars, ctx)
+    if result != 1 {
+        return NewOpenSSLError()
+    }
+    return nil
+}
+
 func InvertECP(group ECGroup, a ECPoint, ctx BNCtx) error {
+    defer isolateErrors()()
     result := C.EC_POINT_invert(group, a, ctx)
     if result != 1 {
         return NewOpenSSLError()
@@ -210,6 +282,7 @@ func InvertECP(group ECGroup, a ECPoint, ctx BNCtx) error {
 }
 
 func SetCompressedCoordsECP(group ECGroup, p ECPoint, x BigNum, yBit int, ctx BNCtx) error {
+    defer isolateErrors()()
     result := C.EC_POINT_set_compressed_coordinates_GFp(group, p, x, C.int(yBit), ctx)
     if result != 1 {
         return NewOpenSSLError()
@@ -217,10 +290,59 @@ func SetCompressedCoordsECP(group ECGroup, p ECPoint, x BigNum, yBit int, ctx BN
     return nil
 }
 
+// OctToECP wraps EC_POINT_oct2point, which sets p from its compressed
+// or uncompressed serialization and checks that it is on the curve.
+//
+// The copy of data made for OpenSSL is cleared before it is freed,
+// since the coordinates may be secret.
+func OctToECP(group ECGroup, p ECPoint, data []byte, ctx BNCtx) error {
+    defer isolateErrors()()
+    cData := C.CBytes(data)
+    defer C.free(cData)
+    defer C.OPENSSL_cleanse(cData, C.size_t(len(data)))
+
+    result := C.EC_POINT_oct2point(group, p, (*C.uchar)(cData), C.size_t(len(data)), ctx)
+    if result != 1 {
+        return NewOpenSSLError()
+    }
+    return nil
+}
+
 func DupECP(src ECPoint, group ECGroup) (ECPoint, error) {
+    defer isolateErrors()()
     var p ECPoint = C.EC_POINT_dup(src, group)
     if p == nil {
         return nil, NewOpenSSLError()
     }
+    trackECPoints(1)
     return p, nil
 }
+
+// CopyECP wraps EC_POINT_copy.
+func CopyECP(dst, src ECPoint) error {
+    defer isolateErrors()()
+    result := C.EC_POINT_copy(dst, src)
+    if result != 1 {
+        return NewOpenSSLError()
+    }
+    return nil
+}
+
+// MakeAffineECPs wraps EC_POINTs_make_affine.
+//
+// It converts the internal representation of all the points to affine
+// coordinates with a single field inversion. The points keep their values.
+func MakeAffineECPs(group ECGroup, points []ECPoint, ctx BNCtx) error {
+    defer isolateErrors()()
+    if len(points) == 0 {
+        return nil
+    }
+
+    // The slice only holds C pointers, so it can be passed to C.
+    cPoints := (**C.EC_POINT)(unsafe.Pointer(&points[0]))
+    result := C.EC_POINTs_make_affine(group, C.size_t(len(points)), cPoints, ctx)
+    if result != 1 {
+        return NewOpenSSLError()
+    }
+    return nil
+}
@@ -19,9 +19,11 @@ package openssl
 // #include "shim.h"
 import "C"
 import (
+    "errors"
     "unsafe"
     "math/big"
     "log"
+    "sync"
 )
 
 type BigNum *C.BIGNUM
@@ -29,19 +31,24 @@ type BNCtx *C.BN_CTX
 type BNMontCtx *C.BN_MONT_CTX
 type ECGroup *C.EC_GROUP
 type ECPoint *C.EC_POINT
+type ECKey *C.EC_KEY
+type ECDSASig *C.ECDSA_SIG
 
 func NewBigNum() BigNum {
+    defer isolateErrors()()
     // bn must be freed later by the calling function.
     var bn BigNum = C.BN_secure_new()
     if bn == nil {
         log.Panic(NewOpenSSLError())
     }
+    trackBigNums(1)
     C.BN_set_flags(bn, C.BN_FLG_CONSTTIME)
     // Both BN_FLG_CONSTTIME and BN_FLG_SECURE are set.
     return bn
 }
 
 func NewBNCtx() BNCtx {
+    defer isolateErrors()()
     var ctx BNCtx = C.BN_CTX_secure_new()
     if ctx == nil {
         log.Panic(NewOpenSSLError())
@@ -49,7 +56,50 @@ func NewBNCtx() BNCtx {
     return ctx
 }
 
+// The largest number of idle BN_CTXs kept by GetBNCtx and PutBNCtx.
+const maxPooledBNCtxs = 64
+
+// A pool of secure BN_CTXs, so that arithmetic does not allocate
+// and free a BN_CTX on every operation.
+var bnCtxPool struct {
+    sync.Mutex
+    free []BNCtx
+}
+
+// GetBNCtx returns a secure BN_CTX from a pool, or a new one
+// if the pool is empty.
+//
+// The BN_CTX must be given back with PutBNCtx once the calling function
+// is done with it, and must not be used by another goroutine meanwhile.
+func GetBNCtx() BNCtx {
+    bnCtxPool.Lock()
+    n := len(bnCtxPool.free)
+    if n > 0 {
+        ctx := bnCtxPool.free[n - 1]
+        bnCtxPool.free = bnCtxPool.free[:n - 1]
+        bnCtxPool.Unlock()
+        return ctx
+    }
+    bnCtxPool.Unlock()
+    return NewBNCtx()
+}
+
+// PutBNCtx gives a BN_CTX from GetBNCtx back to the pool.
+//
+// The BN_CTX is freed if the pool is full.
+func PutBNCtx(ctx BNCtx) {
+    bnCtxPool.Lock()
+    if len(bnCtxPool.free) < maxPooledBNCtxs {
+        bnCtxPool.free = append(bnCtxPool.free, ctx)
+        bnCtxPool.Unlock()
+        return
+    }
+    bnCtxPool.Unlock()
+    FreeBNCtx(ctx)
+}
+
 func NewBNMontCtx() BNMontCtx {
+    defer isolateErrors()()
     var montCtx BNMontCtx = C.BN_MONT_CTX_new()
     if montCtx == nil {
         log.Panic(NewOpenSSLError())
@@ -58,21 +108,31 @@ func NewBNMontCtx() BNMontCtx {
 }
 
 func NewECPoint(curve *Curve) (ECPoint, error) {
+    defer isolateErrors()()
     // newPoint must be freed later by the calling function.
     newPoint := C.EC_POINT_new(curve.Group)
     if newPoint == nil {
         // Invalid Curve Group: New EC Point Failed.
         return nil, NewOpenSSLError()
     }
+    trackECPoints(1)
     return newPoint, nil
 }
 
 func FreeBigNum(bn BigNum) {
+    if bn == nil {
+        return
+    }
     C.BN_clear_free(bn)
+    trackBigNums(-1)
 }
 
 func FreeECPoint(point ECPoint) {
+    if point == nil {
+        return
+    }
     C.EC_POINT_clear_free(point)
+    trackECPoints(-1)
 }
 
 func FreeECGroup(group ECGroup) {
@@ -88,6 +148,7 @@ func FreeBNMontCtx(montCtx BNMontCtx) {
 }
 
 func GetECGroupByCurveNID(curveNid C.int) (ECGroup, error) {
+    defer isolateErrors()()
     // curve must be freed later by the calling function.
     var curve ECGroup = C.EC_GROUP_new_by_curve_name(curveNid)
     if curve == nil {
@@ -98,21 +159,24 @@ func GetECGroupByCurveNID(curveNid C.int) (ECGroup, error) {
 }
 
 func GetECOrderByGroup(group ECGroup) (BigNum, error) {
+    defer isolateErrors()()
     // order must be freed later by the calling function.
     var order BigNum = NewBigNum()
 
-    var ctx BNCtx = NewBNCtx()
-    defer FreeBNCtx(ctx)
+    var ctx BNCtx = GetBNCtx()
+    defer PutBNCtx(ctx)
 
     result := C.EC_GROUP_get_order(group, order, ctx)
     if result != 1 {
         // Invalid Group: Curve Order Lookup Failed.
+        FreeBigNum(order)
         return nil, NewOpenSSLError()
     }
     return order, nil
 }
 
 func GetECGeneratorByGroup(group ECGroup) (ECPoint, error) {
+    defer isolateErrors()()
     // generator should not be freed directly by the calling function.
     // Free the ECGroup instead.
     var generator ECPoint = C.EC_GROUP_get0_generator(group)
@@ -128,58 +192,82 @@ func GetECGroupDegree(group ECGroup) uint {
     return uint(C.EC_GROUP_get_degree(group))
 }
 
+// GetECGroupCurve wraps EC_GROUP_get_curve.
+//
+// It returns the prime p of the field and the coefficients a and b
+// of the curve y^2 = x^3 + a*x + b, which must be freed later
+// by the calling function.
+func GetECGroupCurve(group ECGroup) (BigNum, BigNum, BigNum, error) {
+    defer isolateErrors()()
+    p := NewBigNum()
+    a := NewBigNum()
+    b := NewBigNum()
+
+    ctx := GetBNCtx()
+    defer PutBNCtx(ctx)
+
+    result := C.EC_GROUP_get_curve(group, p, a, b, ctx)
+    if result != 1 {
+        FreeBigNum(p)
+        FreeBigNum(a)
+        FreeBigNum(b)
+        return nil, nil, nil, NewOpenSSLError()
+    }
+    return p, a, b, nil
+}
+
 func BNIsWithinOrder(checkBN BigNum, curve *Curve) bool {
-    zero, err := IntToBN(0)
-    if err != nil {
+    if C.BN_is_zero(checkBN) == 1 || C.BN_is_negative(checkBN) == 1 {
         return false
     }
-    defer FreeBigNum(zero)
-
-    checkSign := C.BN_cmp(checkBN, zero)
-    rangeCheck := C.BN_cmp(checkBN, curve.Order)
-
-    return checkSign == 1 && rangeCheck == -1
+    return C.BN_cmp(checkBN, curve.Order) == -1
 }
 
 func GetECPointFromAffine(affineX, affineY BigNum, curve *Curve) (ECPoint, error) {
+    defer isolateErrors()()
     // newPoint must be freed later by the calling function.
     newPoint, err := NewECPoint(curve)
     if err != nil {
         return nil, err
     }
 
-    var ctx BNCtx = NewBNCtx()
-    defer FreeBNCtx(ctx)
+    var ctx BNCtx = GetBNCtx()
+    defer PutBNCtx(ctx)
 
     result := C.EC_POINT_set_affine_coordinates_GFp(
             curve.Group, newPoint, affineX, affineY, ctx)
     if result != 1 {
         // Invalid Affine or Curve: EC Point Lookup Failed.
+        FreeECPoint(newPoint)
         return nil, NewOpenSSLError()
     }
     return newPoint, nil
 }
 
 func GetAffineCoordsFromECPoint(point ECPoint, curve *Curve) (BigNum, BigNum, error) {
+    defer isolateErrors()()
     // affineX and affineY must be freed later by the calling function.
     var affineX BigNum = NewBigNum()
     var affineY BigNum = NewBigNum()
 
-    var ctx BNCtx = NewBNCtx()
-    defer FreeBNCtx(ctx)
+    var ctx BNCtx = GetBNCtx()
+    defer PutBNCtx(ctx)
 
     result := C.EC_POINT_get_affine_coordinates_GFp(
             curve.Group, point, affineX, affineY, ctx)
     if result != 1 {
         // Invalid ECPoint or Curve: Affine Lookup Failed.
+        FreeBigNum(affineX)
+        FreeBigNum(affineY)
         return nil, nil, NewOpenSSLError()
     }
     return affineX, affineY, nil
 }
 
 func TmpBNMontCTX(modulus BigNum) (BNMontCtx, error) {
-    var ctx BNCtx = NewBNCtx()
-    defer FreeBNCtx(ctx)
+    defer isolateErrors()()
+    var ctx BNCtx = GetBNCtx()
+    defer PutBNCtx(ctx)
 
     // montCtx must be freed later by the calling function.
     var montCtx BNMontCtx = NewBNMontCtx()
@@ -220,18 +308,22 @@ func BytesToBigInt(bytes []byte) *big.Int {
 }
 
 func BytesToBN(bytes []byte) (BigNum, error) {
+    defer isolateErrors()()
     cBytes := C.CBytes(bytes)
     defer C.free(cBytes)
     // cBN must be freed later by the calling function.
-    var cBN BigNum = C.BN_bin2bn((*C.uint8_t)(cBytes), C.int(len(bytes)), NewBigNum())
+    bn := NewBigNum()
+    var cBN BigNum = C.BN_bin2bn((*C.uint8_t)(cBytes), C.int(len(bytes)), bn)
     if cBN == nil {
         // Deserialization Failed.
+        FreeBigNum(bn)
         return nil, NewOpenSSLError()
     }
     return cBN, nil
 }
 
 func BNToBytes(cBN BigNum) ([]byte, error) {
+    defer isolateErrors()()
     var size int = SizeOfBN(cBN)
     var space []byte = make([]byte, size)
     cSpace := C.CBytes(space)
@@ -246,7 +338,28 @@ func BNToBytes(cBN BigNum) ([]byte, error) {
     return bytes, nil
 }
 
+// BNToPaddedBytes wraps BN_bn2binpad.
+//
+// The bytes are left padded with zeros to size, and an error is returned
+// if the BIGNUM does not fit in size bytes.
+func BNToPaddedBytes(cBN BigNum, size int) ([]byte, error) {
+    defer isolateErrors()()
+    if SizeOfBN(cBN) > size {
+        return nil, errors.New("The bignum does not fit in the given size")
+    }
+    cSpace := C.malloc(C.size_t(size))
+    defer C.free(cSpace)
+
+    var written C.int = C.BN_bn2binpad(cBN, (*C.uint8_t)(cSpace), C.int(size))
+    if int(written) != size {
+        // Invalid Written Size: Serialization Failed.
+        return nil, NewOpenSSLError()
+    }
+    return C.GoBytes(cSpace, written), nil
+}
+
 func BNToDecStr(cBN BigNum) string {
+    defer isolateErrors()()
     cString := C.BN_bn2dec(cBN)
     if cString == nil {
         log.Print(NewOpenSSLError())
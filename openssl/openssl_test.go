@@ -248,3 +248,51 @@ func TestRandomRange(t *testing.T) {
             BNToDecStr(cMax))
     }
 }
+
+func TestModExpMontBN(t *testing.T) {
+    curve, err := NewCurve(SECP256K1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer curve.Free()
+
+    base, err := IntToBN(7)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer FreeBigNum(base)
+
+    exp, err := IntToBN(123456789)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer FreeBigNum(exp)
+
+    order, err := BNToBigInt(curve.Order)
+    if err != nil {
+        t.Fatal(err)
+    }
+    expected := new(big.Int).Exp(big.NewInt(7), big.NewInt(123456789), order)
+
+    ctx := GetBNCtx()
+    defer PutBNCtx(ctx)
+
+    // With the cached Montgomery context of the curve and with a temporary one.
+    for _, montCtx := range []BNMontCtx{curve.OrderMontCtx(), nil} {
+        result := NewBigNum()
+        defer FreeBigNum(result)
+
+        err = ModExpMontBN(result, base, exp, curve.Order, ctx, montCtx)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        got, err := BNToBigInt(result)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if got.Cmp(expected) != 0 {
+            t.Error("Got:", got, "Expected:", expected)
+        }
+    }
+}
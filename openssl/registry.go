@@ -0,0 +1,74 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package openssl
+
+// #include "shim.h"
+import "C"
+import (
+    "sync"
+)
+
+// The Curves returned by LookupCurve, by NID.
+var curveRegistry = struct {
+    sync.Mutex
+    byNID map[int]*Curve
+}{byNID: make(map[int]*Curve)}
+
+// RegisterCurve makes the Curve the one returned by LookupCurve for its NID,
+// such as when ModBigNums and Points are decoded.
+//
+// The registry holds a reference to the Curve, so the calling function
+// may still free it.
+func RegisterCurve(curve *Curve) {
+    curve.Retain()
+
+    curveRegistry.Lock()
+    previous := curveRegistry.byNID[curve.NID]
+    curveRegistry.byNID[curve.NID] = curve
+    curveRegistry.Unlock()
+
+    if previous != nil {
+        previous.Release()
+    }
+}
+
+// LookupCurve returns the registered Curve with the NID.
+//
+// If no Curve is registered for a supported NID, a new one is built
+// and registered. The Curve is owned by the registry, and must not be
+// freed by the calling function.
+func LookupCurve(nid int) (*Curve, error) {
+    curveRegistry.Lock()
+    defer curveRegistry.Unlock()
+
+    if curve, ok := curveRegistry.byNID[nid]; ok {
+        return curve, nil
+    }
+
+    if nid != int(C.int(nid)) {
+        return nil, ErrUnsupportedCurve
+    }
+    curve, err := NewCurve(C.int(nid))
+    if err != nil {
+        return nil, err
+    }
+    // The registry holds a reference like for registered Curves,
+    // so that they are all released in the same way.
+    curveRegistry.byNID[nid] = curve.Retain()
+    curve.Free()
+    return curve, nil
+}
@@ -0,0 +1,74 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package openssl
+
+import (
+    "errors"
+    "testing"
+)
+
+func TestLookupCurve(t *testing.T) {
+    curve1, err := LookupCurve(SECP384R1)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    curve2, err := LookupCurve(SECP384R1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if curve1 != curve2 {
+        t.Error("LookupCurve should return the same curve for the same NID")
+    }
+
+    _, err = LookupCurve(0)
+    if !errors.Is(err, ErrUnsupportedCurve) {
+        t.Error("Got:", err, "Expected:", ErrUnsupportedCurve)
+    }
+}
+
+func TestRegisterCurve(t *testing.T) {
+    curve, err := NewCurve(SECP384R1)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // Restore the registered P-384 curve afterwards, for the other tests.
+    previous, err := LookupCurve(SECP384R1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    previous.Retain()
+    defer func() {
+        RegisterCurve(previous)
+        previous.Release()
+    }()
+
+    RegisterCurve(curve)
+    curve.Free()
+
+    registered, err := LookupCurve(SECP384R1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if registered != curve {
+        t.Error("LookupCurve should return the registered curve")
+    }
+    if registered.Group == nil {
+        t.Error("The registry should hold a reference to the curve")
+    }
+}
@@ -16,6 +16,17 @@
 // along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
 #include <stdlib.h>
 #include <openssl/ec.h>
+#include <openssl/ecdsa.h>
 #include <openssl/bn.h>
 #include <openssl/err.h>
 #include <openssl/obj_mac.h>
+
+// ERR_GET_LIB and ERR_GET_REASON are macros in OpenSSL 1.1,
+// which cgo cannot call directly.
+static inline int shim_err_get_lib(unsigned long code) {
+    return ERR_GET_LIB(code);
+}
+
+static inline int shim_err_get_reason(unsigned long code) {
+    return ERR_GET_REASON(code);
+}
@@ -0,0 +1,263 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package umbral
+
+import (
+    "bytes"
+    "errors"
+    "github.com/nucypher/goUmbral/math"
+)
+
+// Represents the encapsulated key of an Umbral ciphertext:
+// the Points E and V, and the ModBigNum s, such that g^s == V * E^h
+// where h is the hash of E and V.
+//
+// A Capsule may also carry the delegating, receiving and verifying keys
+// of a delegation, so that the CFrags attached to it are checked on entry.
+
+type Capsule struct {
+    Params *math.UmbralParameters
+    PointE *math.Point
+    PointV *math.Point
+    BNSig *math.ModBigNum
+
+    delegatingPK *PublicKey
+    receivingPK *PublicKey
+    verifyingPK *PublicKey
+    cfrags []*CFrag
+}
+
+// Returns the size (in bytes) of a serialized Capsule given the parameters.
+func CapsuleLength(params *math.UmbralParameters) uint {
+    pointSize := math.PointLength(params.Curve, true)
+    return 2 * pointSize + math.ExpectedBytesLength(params.Curve)
+}
+
+// Returns the Capsule from its serialization E || V || s.
+func BytesToCapsule(data []byte, params *math.UmbralParameters) (*Capsule, error) {
+    if uint(len(data)) != CapsuleLength(params) {
+        return nil, errors.New("The capsule does not have the right size")
+    }
+    pointSize := math.PointLength(params.Curve, true)
+
+    pointE, err := math.BytesToPoint(data[:pointSize], params.Curve)
+    if err != nil {
+        return nil, err
+    }
+
+    pointV, err := math.BytesToPoint(data[pointSize:2 * pointSize], params.Curve)
+    if err != nil {
+        pointE.Free()
+        return nil, err
+    }
+
+    bnSig, err := math.BytesToModBN(data[2 * pointSize:], params.Curve)
+    if err != nil {
+        pointE.Free()
+        pointV.Free()
+        return nil, err
+    }
+
+    return &Capsule{Params: params, PointE: pointE, PointV: pointV, BNSig: bnSig}, nil
+}
+
+// Returns the Capsule serialized as E || V || s.
+func (m *Capsule) ToBytes() ([]byte, error) {
+    data, err := pointsToBytes(m.PointE, m.PointV)
+    if err != nil {
+        return nil, err
+    }
+
+    sig, err := m.BNSig.Bytes()
+    if err != nil {
+        return nil, err
+    }
+    return append(data, sig...), nil
+}
+
+// Capsule.Verify() checks that g^s == V * E^h where h is the hash of E and V.
+//
+// Verify will return false if the Capsule is malformed and should not be used.
+func (m *Capsule) Verify() (bool, error) {
+    params := m.Params
+
+    h, err := math.HashItemsToModBN(params, nil, m.PointE, m.PointV)
+    if err != nil {
+        return false, err
+    }
+    defer h.Free()
+
+    negH := newModBN(params.Curve)
+    defer negH.Free()
+
+    err = negH.Neg(h)
+    if err != nil {
+        return false, err
+    }
+
+    left, err := newPoint(params.Curve)
+    if err != nil {
+        return false, err
+    }
+    defer left.Free()
+
+    // g^s * E^-h == V
+    err = left.MulAdd(m.BNSig, m.PointE, negH)
+    if err != nil {
+        return false, err
+    }
+
+    return left.Equals(m.PointV)
+}
+
+// Capsule.WithCorrectnessKeys() binds the keys of a delegation to the Capsule,
+// as pyUmbral's set_correctness_keys() does. Any of the keys may be nil.
+//
+// A key cannot be replaced once it is set, and WithCorrectnessKeys will
+// return an error if a different key is given.
+// The Capsule does not take ownership of the keys.
+func (m *Capsule) WithCorrectnessKeys(delegatingPK, receivingPK, verifyingPK *PublicKey) error {
+    keys := []struct {
+        name string
+        current **PublicKey
+        key *PublicKey
+    }{
+        {"delegating", &m.delegatingPK, delegatingPK},
+        {"receiving", &m.receivingPK, receivingPK},
+        {"verifying", &m.verifyingPK, verifyingPK},
+    }
+
+    // Check all the keys first, so that none is set if one fails.
+    for _, k := range keys {
+        if k.key == nil {
+            continue
+        }
+        if !k.key.Params.Equals(m.Params) {
+            return errors.New("The " + k.name + " key does not share the parameters of the capsule")
+        }
+        if *k.current == nil {
+            continue
+        }
+        equal, err := (*k.current).Equals(k.key)
+        if err != nil {
+            return err
+        }
+        if !equal {
+            return errors.New("The " + k.name + " key is already set")
+        }
+    }
+
+    for _, k := range keys {
+        if k.key != nil {
+            *k.current = k.key
+        }
+    }
+    return nil
+}
+
+// Capsule.AttachCFrag() verifies the CFrag against the correctness keys
+// of the Capsule and attaches it if it is correct.
+//
+// AttachCFrag will return a CorrectnessError if the CFrag does not verify,
+// and an error if the correctness keys are not set, if the CFrag shares
+// the KFrag ID of an attached CFrag or comes from another delegation.
+// The Capsule keeps a copy of the CFrag, which is freed along with it,
+// so the caller keeps ownership of cfrag.
+func (m *Capsule) AttachCFrag(cfrag *CFrag) error {
+    if m.delegatingPK == nil || m.receivingPK == nil || m.verifyingPK == nil {
+        return errors.New("The correctness keys of the capsule are not set")
+    }
+
+    err := checkCFrags(append([]*CFrag{cfrag}, m.cfrags...))
+    if err != nil {
+        return err
+    }
+
+    valid, err := cfrag.Verify(m, m.delegatingPK, m.receivingPK, m.verifyingPK)
+    if err != nil {
+        return err
+    }
+    if !valid {
+        return &CorrectnessError{"The cfrag is not correct for this capsule"}
+    }
+
+    attached, err := cfrag.Copy()
+    if err != nil {
+        return err
+    }
+    m.cfrags = append(m.cfrags, attached)
+    return nil
+}
+
+// Returns the copies of the CFrags attached to the Capsule,
+// which are owned by the Capsule.
+func (m *Capsule) AttachedCFrags() []*CFrag {
+    return m.cfrags
+}
+
+// Returns an error if two of the CFrags share the same KFrag ID
+// or if they do not all come from the same delegation.
+func checkCFrags(cfrags []*CFrag) error {
+    for i, cfrag := range cfrags {
+        for _, other := range cfrags[:i] {
+            if bytes.Equal(cfrag.KFragID, other.KFragID) {
+                return errors.New("Two cfrags share the same kfrag id")
+            }
+        }
+
+        eNI, err := cfrag.PointNonInteractive.Equals(cfrags[0].PointNonInteractive)
+        if err != nil {
+            return err
+        }
+
+        eXCoord, err := cfrag.PointXCoord.Equals(cfrags[0].PointXCoord)
+        if err != nil {
+            return err
+        }
+
+        if !eNI || !eXCoord {
+            return errors.New("The cfrags do not come from the same delegation")
+        }
+    }
+    return nil
+}
+
+func (m *Capsule) Equals(other *Capsule) (bool, error) {
+    eE, err := m.PointE.Equals(other.PointE)
+    if err != nil {
+        return false, err
+    }
+
+    eV, err := m.PointV.Equals(other.PointV)
+    if err != nil {
+        return false, err
+    }
+
+    eSig := m.BNSig.Equals(other.BNSig)
+
+    return eE && eV && eSig, nil
+}
+
+func (m *Capsule) Free() {
+    m.PointE.Free()
+    m.PointV.Free()
+    m.BNSig.Free()
+    for _, cfrag := range m.cfrags {
+        cfrag.Free()
+    }
+    m.cfrags = nil
+}
@@ -0,0 +1,265 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package umbral_test
+
+import (
+    "bytes"
+    "errors"
+    "testing"
+    "encoding/json"
+    "encoding/hex"
+    "io/ioutil"
+    "github.com/nucypher/goUmbral/math"
+    "github.com/nucypher/goUmbral/openssl"
+    "github.com/nucypher/goUmbral/umbral"
+)
+
+type CFragVectors struct {
+    Capsule string `json:"capsule"`
+    VerifyingKey string `json:"verifying_key"`
+    DelegatingKey string `json:"delegating_key"`
+    ReceivingKey string `json:"receiving_key"`
+    Vectors []CFragVector `json:"vectors"`
+}
+
+type CFragVector struct {
+    KFrag string `json:"kfrag"`
+    CFrag string `json:"cfrag"`
+}
+
+func TestCapsuleVector(t *testing.T) {
+    data, err := ioutil.ReadFile("../vectors/vectors_cfrags.json")
+    if err != nil {
+        t.Error(err)
+    }
+
+    var vectors CFragVectors
+    err = json.Unmarshal(data, &vectors)
+    if err != nil {
+        t.Error(err)
+    }
+
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Error(err)
+    }
+    defer curve.Free()
+
+    params, err := math.NewUmbralParameters(curve)
+    if err != nil {
+        t.Error(err)
+    }
+
+    capsuleBytes, err := hex.DecodeString(vectors.Capsule)
+    if err != nil {
+        t.Error(err)
+    }
+
+    capsule, err := umbral.BytesToCapsule(capsuleBytes, params)
+    if err != nil {
+        t.Error(err)
+    }
+    defer capsule.Free()
+
+    valid, err := capsule.Verify()
+    if err != nil {
+        t.Error(err)
+    }
+    if !valid {
+        t.Error("The capsule vector did not verify")
+    }
+
+    result, err := capsule.ToBytes()
+    if err != nil {
+        t.Error(err)
+    }
+    if !bytes.Equal(capsuleBytes, result) {
+        t.Error("The capsule did not serialize to the vector")
+    }
+
+    // Swapping E and V must make the capsule invalid.
+    swapped := append(append([]byte{}, capsuleBytes[33:66]...), capsuleBytes[:33]...)
+    swapped = append(swapped, capsuleBytes[66:]...)
+
+    badCapsule, err := umbral.BytesToCapsule(swapped, params)
+    if err != nil {
+        t.Error(err)
+    }
+    defer badCapsule.Free()
+
+    valid, err = badCapsule.Verify()
+    if err != nil {
+        t.Error(err)
+    }
+    if valid {
+        t.Error("A malformed capsule was verified")
+    }
+}
+
+func TestBytesToCapsuleWrongSize(t *testing.T) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Error(err)
+    }
+    defer curve.Free()
+
+    params, err := math.NewUmbralParameters(curve)
+    if err != nil {
+        t.Error(err)
+    }
+
+    _, err = umbral.BytesToCapsule(make([]byte, 97), params)
+    if err == nil {
+        t.Error("A capsule of the wrong size was deserialized")
+    }
+}
+
+func TestAttachCFrag(t *testing.T) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Error(err)
+    }
+    defer curve.Free()
+
+    params, err := math.NewUmbralParameters(curve)
+    if err != nil {
+        t.Error(err)
+    }
+
+    delegatingKey, err := umbral.GenPrivateKey(params)
+    if err != nil {
+        t.Error(err)
+    }
+    defer delegatingKey.Free()
+
+    receivingKey, err := umbral.GenPrivateKey(params)
+    if err != nil {
+        t.Error(err)
+    }
+    defer receivingKey.Free()
+
+    signingKey, err := umbral.GenPrivateKey(params)
+    if err != nil {
+        t.Error(err)
+    }
+    defer signingKey.Free()
+
+    signer := umbral.NewSigner(signingKey)
+
+    plaintext := []byte("peace at dawn")
+
+    ciphertext, capsule, err := umbral.Encrypt(delegatingKey.GetPublicKey(), plaintext)
+    if err != nil {
+        t.Error(err)
+    }
+    defer capsule.Free()
+
+    _, otherCapsule, err := umbral.Encrypt(delegatingKey.GetPublicKey(), plaintext)
+    if err != nil {
+        t.Error(err)
+    }
+    defer otherCapsule.Free()
+
+    kfrags, err := umbral.GenerateKFrags(delegatingKey, receivingKey.GetPublicKey(),
+        signer, 2, 3)
+    if err != nil {
+        t.Error(err)
+    }
+
+    cfrags := make([]*umbral.CFrag, len(kfrags))
+    for i, kfrag := range kfrags {
+        defer kfrag.Free()
+
+        cfrags[i], err = umbral.Reencrypt(kfrag, capsule, true, nil)
+        if err != nil {
+            t.Error(err)
+        }
+        defer cfrags[i].Free()
+    }
+
+    otherCFrag, err := umbral.Reencrypt(kfrags[0], otherCapsule, true, nil)
+    if err != nil {
+        t.Error(err)
+    }
+    defer otherCFrag.Free()
+
+    err = capsule.AttachCFrag(cfrags[0])
+    if err == nil {
+        t.Error("A cfrag was attached without the correctness keys")
+    }
+
+    err = capsule.WithCorrectnessKeys(delegatingKey.GetPublicKey(),
+        receivingKey.GetPublicKey(), signer.GetPublicKey())
+    if err != nil {
+        t.Error(err)
+    }
+
+    // Setting the same keys again is allowed, but not replacing them.
+    err = capsule.WithCorrectnessKeys(delegatingKey.GetPublicKey(), nil, nil)
+    if err != nil {
+        t.Error(err)
+    }
+    err = capsule.WithCorrectnessKeys(nil, delegatingKey.GetPublicKey(), nil)
+    if err == nil {
+        t.Error("The receiving key of the capsule was replaced")
+    }
+
+    err = capsule.AttachCFrag(otherCFrag)
+    if _, ok := err.(*umbral.CorrectnessError); !ok {
+        t.Error("A cfrag of another capsule was attached")
+    }
+
+    err = capsule.AttachCFrag(cfrags[0])
+    if err != nil {
+        t.Error(err)
+    }
+
+    err = capsule.AttachCFrag(cfrags[0])
+    if err == nil {
+        t.Error("The same cfrag was attached twice")
+    }
+
+    _, err = umbral.Decrypt(receivingKey, capsule, ciphertext)
+    if !errors.Is(err, umbral.ErrNotEnoughCFrags) {
+        t.Error("Decryption with fewer cfrags than the threshold did not fail with ErrNotEnoughCFrags")
+    }
+
+    err = capsule.AttachCFrag(cfrags[1])
+    if err != nil {
+        t.Error(err)
+    }
+
+    if len(capsule.AttachedCFrags()) != 2 {
+        t.Error("The capsule does not have two attached cfrags")
+    }
+    if capsule.AttachedCFrags()[1] == cfrags[1] {
+        t.Error("The capsule did not attach a copy of the cfrag")
+    }
+
+    _, err = umbral.Decrypt(delegatingKey, capsule, ciphertext)
+    if err == nil {
+        t.Error("A capsule with attached cfrags was decrypted with the delegating key")
+    }
+
+    cleartext, err := umbral.Decrypt(receivingKey, capsule, ciphertext)
+    if err != nil {
+        t.Error(err)
+    }
+    if !bytes.Equal(plaintext, cleartext) {
+        t.Error("The decrypted data was not equal to the plaintext")
+    }
+}
@@ -0,0 +1,237 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package umbral
+
+import (
+    "errors"
+    "github.com/nucypher/goUmbral/math"
+)
+
+// Represents a fragment of a Capsule re-encrypted with a KFrag:
+// the Points E1 = E^rk and V1 = V^rk, along with the ID, the precursor
+// and the x coordinate point of the KFrag.
+//
+// The CorrectnessProof is nil unless it was requested on re-encryption.
+
+type CFrag struct {
+    PointE1 *math.Point
+    PointV1 *math.Point
+    KFragID []byte
+    PointNonInteractive *math.Point
+    PointXCoord *math.Point
+    Proof *CorrectnessProof
+}
+
+// Returns the size (in bytes) of a serialized CFrag given the parameters.
+func CFragLength(params *math.UmbralParameters) uint {
+    bnSize := math.ExpectedBytesLength(params.Curve)
+    pointSize := math.PointLength(params.Curve, true)
+    return bnSize + 4 * pointSize
+}
+
+// Returns the CFrag from its serialization
+// e1 || v1 || kfrag id || precursor || xcoord || proof,
+// where the CorrectnessProof is optional.
+func BytesToCFrag(data []byte, params *math.UmbralParameters) (*CFrag, error) {
+    if uint(len(data)) < CFragLength(params) {
+        return nil, errors.New("The cfrag is too short")
+    }
+    curve := params.Curve
+    bnSize := int(math.ExpectedBytesLength(curve))
+    pointSize := int(math.PointLength(curve, true))
+
+    pointE1, err := math.BytesToPoint(data[:pointSize], curve)
+    if err != nil {
+        return nil, err
+    }
+    data = data[pointSize:]
+
+    pointV1, err := math.BytesToPoint(data[:pointSize], curve)
+    if err != nil {
+        pointE1.Free()
+        return nil, err
+    }
+    data = data[pointSize:]
+
+    kfragID := append([]byte{}, data[:bnSize]...)
+    data = data[bnSize:]
+
+    ni, err := math.BytesToPoint(data[:pointSize], curve)
+    if err != nil {
+        pointE1.Free()
+        pointV1.Free()
+        return nil, err
+    }
+    data = data[pointSize:]
+
+    xcoord, err := math.BytesToPoint(data[:pointSize], curve)
+    if err != nil {
+        pointE1.Free()
+        pointV1.Free()
+        ni.Free()
+        return nil, err
+    }
+    data = data[pointSize:]
+
+    cfrag := &CFrag{PointE1: pointE1, PointV1: pointV1, KFragID: kfragID,
+        PointNonInteractive: ni, PointXCoord: xcoord}
+
+    if len(data) > 0 {
+        cfrag.Proof, err = BytesToCorrectnessProof(data, params)
+        if err != nil {
+            cfrag.Free()
+            return nil, err
+        }
+    }
+    return cfrag, nil
+}
+
+// Returns the CFrag serialized as
+// e1 || v1 || kfrag id || precursor || xcoord || proof,
+// where the CorrectnessProof is only present if the CFrag has one.
+func (m *CFrag) ToBytes() ([]byte, error) {
+    data, err := pointsToBytes(m.PointE1, m.PointV1)
+    if err != nil {
+        return nil, err
+    }
+
+    points, err := pointsToBytes(m.PointNonInteractive, m.PointXCoord)
+    if err != nil {
+        return nil, err
+    }
+
+    data = append(data, m.KFragID...)
+    data = append(data, points...)
+
+    if m.Proof != nil {
+        proof, err := m.Proof.ToBytes()
+        if err != nil {
+            return nil, err
+        }
+        data = append(data, proof...)
+    }
+    return data, nil
+}
+
+// CFrag.Verify() checks the CorrectnessProof of the CFrag: that it is the
+// re-encryption of the Capsule with a KFrag signed by verifyingPK for the
+// delegation from delegatingPK to receivingPK.
+//
+// Verify will return an error if the CFrag does not have a CorrectnessProof.
+func (m *CFrag) Verify(capsule *Capsule, delegatingPK, receivingPK, verifyingPK *PublicKey) (bool, error) {
+    proof := m.Proof
+    if proof == nil {
+        return false, errors.New("The cfrag does not have a correctness proof")
+    }
+    params := capsule.Params
+
+    h, err := proofChallenge(capsule, m, proof)
+    if err != nil {
+        return false, err
+    }
+    defer h.Free()
+
+    message, err := kfragValidityMessage(m.KFragID, delegatingPK, receivingPK,
+        proof.PointKFragCommitment, m.PointNonInteractive, m.PointXCoord)
+    if err != nil {
+        return false, err
+    }
+
+    validSignature, err := proof.KFragSignature.Verify(message, verifyingPK)
+    if err != nil {
+        return false, err
+    }
+
+    // z3 * e == e2 + h * e1
+    correctE, err := checkProofEquation(proof.BNSig, capsule.PointE, proof.PointE2, h, m.PointE1)
+    if err != nil {
+        return false, err
+    }
+
+    // z3 * v == v2 + h * v1
+    correctV, err := checkProofEquation(proof.BNSig, capsule.PointV, proof.PointV2, h, m.PointV1)
+    if err != nil {
+        return false, err
+    }
+
+    // z3 * u == u2 + h * u1
+    correctCommitment, err := checkProofEquation(proof.BNSig, params.U, proof.PointKFragPok,
+        h, proof.PointKFragCommitment)
+    if err != nil {
+        return false, err
+    }
+
+    return validSignature && correctE && correctV && correctCommitment, nil
+}
+
+func (m *CFrag) Copy() (*CFrag, error) {
+    pointE1, err := m.PointE1.Copy()
+    if err != nil {
+        return nil, err
+    }
+
+    pointV1, err := m.PointV1.Copy()
+    if err != nil {
+        pointE1.Free()
+        return nil, err
+    }
+
+    ni, err := m.PointNonInteractive.Copy()
+    if err != nil {
+        pointE1.Free()
+        pointV1.Free()
+        return nil, err
+    }
+
+    xcoord, err := m.PointXCoord.Copy()
+    if err != nil {
+        pointE1.Free()
+        pointV1.Free()
+        ni.Free()
+        return nil, err
+    }
+
+    cfrag := &CFrag{PointE1: pointE1, PointV1: pointV1, KFragID: append([]byte{}, m.KFragID...),
+        PointNonInteractive: ni, PointXCoord: xcoord}
+
+    if m.Proof != nil {
+        cfrag.Proof, err = m.Proof.Copy()
+        if err != nil {
+            cfrag.Free()
+            return nil, err
+        }
+    }
+    return cfrag, nil
+}
+
+func (m *CFrag) Free() {
+    if m.PointE1 != nil {
+        m.PointE1.Free()
+    }
+    if m.PointV1 != nil {
+        m.PointV1.Free()
+    }
+    if m.PointNonInteractive != nil {
+        m.PointNonInteractive.Free()
+    }
+    if m.PointXCoord != nil {
+        m.PointXCoord.Free()
+    }
+    if m.Proof != nil {
+        m.Proof.Free()
+    }
+}
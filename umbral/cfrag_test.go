@@ -0,0 +1,243 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package umbral_test
+
+import (
+    "bytes"
+    "testing"
+    "encoding/json"
+    "encoding/hex"
+    "io/ioutil"
+    "github.com/nucypher/goUmbral/math"
+    "github.com/nucypher/goUmbral/openssl"
+    "github.com/nucypher/goUmbral/umbral"
+)
+
+func TestCFragVectors(t *testing.T) {
+    data, err := ioutil.ReadFile("../vectors/vectors_cfrags.json")
+    if err != nil {
+        t.Error(err)
+    }
+
+    var vectors CFragVectors
+    err = json.Unmarshal(data, &vectors)
+    if err != nil {
+        t.Error(err)
+    }
+
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Error(err)
+    }
+    defer curve.Free()
+
+    params, err := math.NewUmbralParameters(curve)
+    if err != nil {
+        t.Error(err)
+    }
+
+    capsuleBytes, err := hex.DecodeString(vectors.Capsule)
+    if err != nil {
+        t.Error(err)
+    }
+
+    capsule, err := umbral.BytesToCapsule(capsuleBytes, params)
+    if err != nil {
+        t.Error(err)
+    }
+    defer capsule.Free()
+
+    for _, k := range vectors.Vectors {
+        kfragBytes, err := hex.DecodeString(k.KFrag)
+        if err != nil {
+            t.Error(err)
+        }
+
+        cfragBytes, err := hex.DecodeString(k.CFrag)
+        if err != nil {
+            t.Error(err)
+        }
+
+        kfrag, err := umbral.BytesToKFrag(kfragBytes, params)
+        if err != nil {
+            t.Error(err)
+        }
+        defer kfrag.Free()
+
+        cfrag, err := umbral.Reencrypt(kfrag, capsule, false, nil)
+        if err != nil {
+            t.Error(err)
+        }
+        defer cfrag.Free()
+
+        result, err := cfrag.ToBytes()
+        if err != nil {
+            t.Error(err)
+        }
+        if !bytes.Equal(cfragBytes, result) {
+            t.Error("The re-encrypted cfrag was not equal to the vector:", k.CFrag)
+        }
+
+        newCFrag, err := umbral.BytesToCFrag(cfragBytes, params)
+        if err != nil {
+            t.Error(err)
+        }
+        defer newCFrag.Free()
+
+        result, err = newCFrag.ToBytes()
+        if err != nil {
+            t.Error(err)
+        }
+        if !bytes.Equal(cfragBytes, result) {
+            t.Error("The cfrag did not serialize to the vector:", k.CFrag)
+        }
+    }
+}
+
+func TestCFragCorrectnessProof(t *testing.T) {
+    data, err := ioutil.ReadFile("../vectors/vectors_cfrags.json")
+    if err != nil {
+        t.Error(err)
+    }
+
+    var vectors CFragVectors
+    err = json.Unmarshal(data, &vectors)
+    if err != nil {
+        t.Error(err)
+    }
+
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Error(err)
+    }
+    defer curve.Free()
+
+    params, err := math.NewUmbralParameters(curve)
+    if err != nil {
+        t.Error(err)
+    }
+
+    keys := make([]*umbral.PublicKey, 3)
+    for i, k := range []string{vectors.VerifyingKey, vectors.DelegatingKey, vectors.ReceivingKey} {
+        keyBytes, err := hex.DecodeString(k)
+        if err != nil {
+            t.Error(err)
+        }
+
+        keys[i], err = umbral.BytesToPublicKey(keyBytes, params)
+        if err != nil {
+            t.Error(err)
+        }
+        defer keys[i].Free()
+    }
+    verifyingKey, delegatingKey, receivingKey := keys[0], keys[1], keys[2]
+
+    capsuleBytes, err := hex.DecodeString(vectors.Capsule)
+    if err != nil {
+        t.Error(err)
+    }
+
+    capsule, err := umbral.BytesToCapsule(capsuleBytes, params)
+    if err != nil {
+        t.Error(err)
+    }
+    defer capsule.Free()
+
+    kfrags := make([]*umbral.KFrag, 2)
+    for i := range kfrags {
+        kfragBytes, err := hex.DecodeString(vectors.Vectors[i].KFrag)
+        if err != nil {
+            t.Error(err)
+        }
+
+        kfrags[i], err = umbral.BytesToKFrag(kfragBytes, params)
+        if err != nil {
+            t.Error(err)
+        }
+        defer kfrags[i].Free()
+    }
+
+    for _, metadata := range [][]byte{nil, []byte("This is an example of metadata for re-encryption request")} {
+        cfrag, err := umbral.Reencrypt(kfrags[0], capsule, true, metadata)
+        if err != nil {
+            t.Error(err)
+        }
+        defer cfrag.Free()
+
+        valid, err := cfrag.Verify(capsule, delegatingKey, receivingKey, verifyingKey)
+        if err != nil {
+            t.Error(err)
+        }
+        if !valid {
+            t.Error("A correct cfrag did not verify")
+        }
+
+        cfragBytes, err := cfrag.ToBytes()
+        if err != nil {
+            t.Error(err)
+        }
+
+        newCFrag, err := umbral.BytesToCFrag(cfragBytes, params)
+        if err != nil {
+            t.Error(err)
+        }
+        defer newCFrag.Free()
+
+        if !bytes.Equal(metadata, newCFrag.Proof.Metadata) {
+            t.Error("The metadata was not equal after deserialization")
+        }
+
+        valid, err = newCFrag.Verify(capsule, delegatingKey, receivingKey, verifyingKey)
+        if err != nil {
+            t.Error(err)
+        }
+        if !valid {
+            t.Error("A deserialized correct cfrag did not verify")
+        }
+
+        // A cfrag checked against the wrong keys must not verify.
+        valid, err = cfrag.Verify(capsule, receivingKey, delegatingKey, verifyingKey)
+        if err != nil {
+            t.Error(err)
+        }
+        if valid {
+            t.Error("A cfrag verified under the wrong keys")
+        }
+
+        // A proxy lying about the re-encryption must be caught.
+        otherCFrag, err := umbral.Reencrypt(kfrags[1], capsule, false, nil)
+        if err != nil {
+            t.Error(err)
+        }
+        defer otherCFrag.Free()
+
+        cfrag.PointE1, otherCFrag.PointE1 = otherCFrag.PointE1, cfrag.PointE1
+
+        valid, err = cfrag.Verify(capsule, delegatingKey, receivingKey, verifyingKey)
+        if err != nil {
+            t.Error(err)
+        }
+        if valid {
+            t.Error("An incorrectly re-encrypted cfrag was verified")
+        }
+
+        _, err = otherCFrag.Verify(capsule, delegatingKey, receivingKey, verifyingKey)
+        if err == nil {
+            t.Error("A cfrag without a correctness proof should have returned an error")
+        }
+    }
+}
@@ -0,0 +1,72 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package umbral
+
+import (
+    "crypto/cipher"
+    "crypto/rand"
+    "golang.org/x/crypto/chacha20poly1305"
+)
+
+// The size (in bytes) of the symmetric keys derived from a Capsule.
+const DEMKeySize = chacha20poly1305.KeySize
+
+// The size (in bytes) of the random nonce prepended to every ciphertext.
+const DEMNonceSize = chacha20poly1305.NonceSize
+
+// Represents the Data Encapsulation Mechanism of Umbral:
+// ChaCha20-Poly1305 keyed with the symmetric key of a Capsule.
+
+type DEM struct {
+    cipher cipher.AEAD
+}
+
+func NewDEM(key []byte) (*DEM, error) {
+    aead, err := chacha20poly1305.New(key)
+    if err != nil {
+        return nil, err
+    }
+    return &DEM{aead}, nil
+}
+
+// Returns the data encrypted and authenticated along with authenticatedData.
+// The ciphertext is prefixed with the random nonce used to encrypt it.
+func (m *DEM) Encrypt(data, authenticatedData []byte) ([]byte, error) {
+    nonce := make([]byte, DEMNonceSize)
+    _, err := rand.Read(nonce)
+    if err != nil {
+        return nil, err
+    }
+    return m.cipher.Seal(nonce, nonce, data, authenticatedData), nil
+}
+
+// Returns the plaintext of a ciphertext produced by DEM.Encrypt().
+//
+// Decrypt will return an AuthenticationError if the ciphertext
+// or authenticatedData have been modified.
+func (m *DEM) Decrypt(ciphertext, authenticatedData []byte) ([]byte, error) {
+    if len(ciphertext) < DEMNonceSize + m.cipher.Overhead() {
+        return nil, &AuthenticationError{"The ciphertext is too short"}
+    }
+    nonce := ciphertext[:DEMNonceSize]
+
+    plaintext, err := m.cipher.Open(nil, nonce, ciphertext[DEMNonceSize:], authenticatedData)
+    if err != nil {
+        return nil, &AuthenticationError{"The ciphertext failed to authenticate"}
+    }
+    return plaintext, nil
+}
@@ -0,0 +1,47 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package umbral
+
+import (
+    "errors"
+    "fmt"
+)
+
+// ErrNotEnoughCFrags is returned by DecryptReencrypted when the CFrags
+// attached to a Capsule are correct, but fewer than the threshold
+// of their delegation.
+var ErrNotEnoughCFrags = errors.New("Fewer cfrags than the threshold were provided")
+
+// AuthenticationError is returned when a ciphertext or its Capsule
+// fails to authenticate, i.e. when either of them has been tampered with.
+type AuthenticationError struct {
+    Reason string
+}
+
+func (m *AuthenticationError) Error() string {
+    return fmt.Sprintf("Umbral Authentication Error: %s", m.Reason)
+}
+
+// CorrectnessError is returned when a CFrag fails to verify
+// against the Capsule and the keys of its delegation.
+type CorrectnessError struct {
+    Reason string
+}
+
+func (m *CorrectnessError) Error() string {
+    return fmt.Sprintf("Umbral Correctness Error: %s", m.Reason)
+}
@@ -0,0 +1,145 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package umbral
+
+import (
+    "errors"
+    "github.com/nucypher/goUmbral/math"
+)
+
+// Represents an Umbral private key: a ModBigNum modulo the order of the
+// curve of the UmbralParameters, along with its associated public key.
+
+type PrivateKey struct {
+    Params *math.UmbralParameters
+    BNKey *math.ModBigNum
+    PubKey *PublicKey
+}
+
+// Represents an Umbral public key: a Point on the curve of the
+// UmbralParameters.
+
+type PublicKey struct {
+    Params *math.UmbralParameters
+    PointKey *math.Point
+}
+
+// Returns a PrivateKey for the given ModBigNum and computes
+// its PublicKey as bnKey * G.
+//
+// The PrivateKey takes ownership of bnKey.
+func NewPrivateKey(bnKey *math.ModBigNum, params *math.UmbralParameters) (*PrivateKey, error) {
+    if !bnKey.Curve.Equals(params.Curve) {
+        return nil, errors.New("The private key does not share the curve of the parameters.")
+    }
+
+    pointKey, err := newPoint(params.Curve)
+    if err != nil {
+        return nil, err
+    }
+
+    err = pointKey.Mul(params.G, bnKey)
+    if err != nil {
+        pointKey.Free()
+        return nil, err
+    }
+
+    pubKey := &PublicKey{Params: params, PointKey: pointKey}
+    return &PrivateKey{Params: params, BNKey: bnKey, PubKey: pubKey}, nil
+}
+
+// Returns a cryptographically secure PrivateKey
+// on the curve of the given parameters.
+func GenPrivateKey(params *math.UmbralParameters) (*PrivateKey, error) {
+    bnKey, err := math.GenRandModBN(params.Curve)
+    if err != nil {
+        return nil, err
+    }
+
+    privKey, err := NewPrivateKey(bnKey, params)
+    if err != nil {
+        bnKey.Free()
+        return nil, err
+    }
+    return privKey, nil
+}
+
+// Returns the PrivateKey from the bytes produced by PrivateKey.ToBytes().
+func BytesToPrivateKey(data []byte, params *math.UmbralParameters) (*PrivateKey, error) {
+    bnKey, err := math.BytesToModBN(data, params.Curve)
+    if err != nil {
+        return nil, err
+    }
+
+    privKey, err := NewPrivateKey(bnKey, params)
+    if err != nil {
+        bnKey.Free()
+        return nil, err
+    }
+    return privKey, nil
+}
+
+// Returns the PrivateKey serialized as bytes.
+//
+// The private key is always encoded with the size of the curve order.
+func (m *PrivateKey) ToBytes() ([]byte, error) {
+    return m.BNKey.Bytes()
+}
+
+func (m *PrivateKey) GetPublicKey() *PublicKey {
+    return m.PubKey
+}
+
+func (m *PrivateKey) Free() {
+    m.BNKey.Free()
+    m.PubKey.Free()
+}
+
+// Returns a PublicKey for the given Point.
+//
+// The PublicKey takes ownership of pointKey.
+func NewPublicKey(pointKey *math.Point, params *math.UmbralParameters) (*PublicKey, error) {
+    if !pointKey.Curve.Equals(params.Curve) {
+        return nil, errors.New("The public key does not share the curve of the parameters.")
+    }
+    return &PublicKey{Params: params, PointKey: pointKey}, nil
+}
+
+// Returns the PublicKey from its compressed or uncompressed serialization.
+func BytesToPublicKey(data []byte, params *math.UmbralParameters) (*PublicKey, error) {
+    pointKey, err := math.BytesToPoint(data, params.Curve)
+    if err != nil {
+        return nil, err
+    }
+    return &PublicKey{Params: params, PointKey: pointKey}, nil
+}
+
+// Returns the PublicKey serialized as a compressed Point.
+func (m *PublicKey) ToBytes() ([]byte, error) {
+    return m.PointKey.ToBytes(true)
+}
+
+func (m *PublicKey) Equals(other *PublicKey) (bool, error) {
+    if !m.Params.Equals(other.Params) {
+        return false, nil
+    }
+    return m.PointKey.Equals(other.PointKey)
+}
+
+func (m *PublicKey) Free() {
+    m.PointKey.Free()
+}
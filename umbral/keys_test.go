@@ -0,0 +1,136 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package umbral_test
+
+import (
+    "bytes"
+    "testing"
+    "encoding/json"
+    "encoding/hex"
+    "io/ioutil"
+    "github.com/nucypher/goUmbral/math"
+    "github.com/nucypher/goUmbral/openssl"
+    "github.com/nucypher/goUmbral/umbral"
+)
+
+type KeyVectors struct {
+    VerifyingKey string `json:"verifying_key"`
+    DelegatingKey string `json:"delegating_key"`
+    ReceivingKey string `json:"receiving_key"`
+}
+
+func TestGenPrivateKey(t *testing.T) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Error(err)
+    }
+    defer curve.Free()
+
+    params, err := math.NewUmbralParameters(curve)
+    if err != nil {
+        t.Error(err)
+    }
+
+    for i := 0; i < 100; i++ {
+        privKey, err := umbral.GenPrivateKey(params)
+        if err != nil {
+            t.Error(err)
+        }
+
+        data, err := privKey.ToBytes()
+        if err != nil {
+            t.Error(err)
+        }
+        if len(data) != 32 {
+            t.Error("The private key was not 32 bytes long:", len(data))
+        }
+
+        newKey, err := umbral.BytesToPrivateKey(data, params)
+        if err != nil {
+            t.Error(err)
+        }
+
+        if !privKey.BNKey.Equals(newKey.BNKey) {
+            t.Error("The private keys were not equal after deserialization")
+        }
+
+        equal, err := privKey.GetPublicKey().Equals(newKey.GetPublicKey())
+        if err != nil {
+            t.Error(err)
+        }
+        if !equal {
+            t.Error("The public keys were not equal after deserialization")
+        }
+
+        pubBytes, err := privKey.GetPublicKey().ToBytes()
+        if err != nil {
+            t.Error(err)
+        }
+        if len(pubBytes) != 33 {
+            t.Error("The public key was not 33 bytes long:", len(pubBytes))
+        }
+
+        privKey.Free()
+        newKey.Free()
+    }
+}
+
+func TestPublicKeyVectors(t *testing.T) {
+    data, err := ioutil.ReadFile("../vectors/vectors_kfrags.json")
+    if err != nil {
+        t.Error(err)
+    }
+
+    var vectors KeyVectors
+    err = json.Unmarshal(data, &vectors)
+    if err != nil {
+        t.Error(err)
+    }
+
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Error(err)
+    }
+    defer curve.Free()
+
+    params, err := math.NewUmbralParameters(curve)
+    if err != nil {
+        t.Error(err)
+    }
+
+    for _, k := range []string{vectors.VerifyingKey, vectors.DelegatingKey, vectors.ReceivingKey} {
+        keyBytes, err := hex.DecodeString(k)
+        if err != nil {
+            t.Error(err)
+        }
+
+        pubKey, err := umbral.BytesToPublicKey(keyBytes, params)
+        if err != nil {
+            t.Error(err)
+        }
+
+        result, err := pubKey.ToBytes()
+        if err != nil {
+            t.Error(err)
+        }
+
+        if !bytes.Equal(keyBytes, result) {
+            t.Error("The public key did not serialize to the vector:", k)
+        }
+        pubKey.Free()
+    }
+}
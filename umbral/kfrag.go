@@ -0,0 +1,379 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package umbral
+
+import (
+    "crypto/rand"
+    "errors"
+    "github.com/nucypher/goUmbral/math"
+)
+
+// Represents a fragment of a re-encryption key from a delegating key to
+// a receiving key, as produced by pyUmbral's split_rekey.
+//
+// PointNonInteractive is the precursor of the KFrag: the ephemeral public
+// key of a Diffie-Hellman exchange with the receiving key that makes the
+// re-encryption non-interactive. PointXCoord is the ephemeral public key of
+// a second exchange from which the x coordinates of the shares are derived.
+// Both are shared by all the KFrags of a single delegation.
+
+type KFrag struct {
+    ID []byte
+    BNKey *math.ModBigNum
+    PointNonInteractive *math.Point
+    PointCommitment *math.Point
+    PointXCoord *math.Point
+    Signature *Signature
+}
+
+// Returns the size (in bytes) of a serialized KFrag given the parameters.
+func KFragLength(params *math.UmbralParameters) uint {
+    bnSize := math.ExpectedBytesLength(params.Curve)
+    pointSize := math.PointLength(params.Curve, true)
+    return 2 * bnSize + 3 * pointSize + SignatureLength(params.Curve)
+}
+
+// Returns the KFrag from its serialization
+// id || rk || precursor || commitment || xcoord || signature.
+func BytesToKFrag(data []byte, params *math.UmbralParameters) (*KFrag, error) {
+    if uint(len(data)) != KFragLength(params) {
+        return nil, errors.New("The kfrag does not have the right size")
+    }
+    curve := params.Curve
+    bnSize := int(math.ExpectedBytesLength(curve))
+    pointSize := int(math.PointLength(curve, true))
+
+    kfrag := &KFrag{ID: append([]byte{}, data[:bnSize]...)}
+    data = data[bnSize:]
+
+    var err error
+    kfrag.BNKey, err = math.BytesToModBN(data[:bnSize], curve)
+    if err != nil {
+        return nil, err
+    }
+    data = data[bnSize:]
+
+    points := make([]*math.Point, 3)
+    for i := range points {
+        points[i], err = math.BytesToPoint(data[:pointSize], curve)
+        if err != nil {
+            for _, point := range points[:i] {
+                point.Free()
+            }
+            kfrag.Free()
+            return nil, err
+        }
+        data = data[pointSize:]
+    }
+    kfrag.PointNonInteractive = points[0]
+    kfrag.PointCommitment = points[1]
+    kfrag.PointXCoord = points[2]
+
+    kfrag.Signature, err = BytesToSignature(data, curve)
+    if err != nil {
+        kfrag.Free()
+        return nil, err
+    }
+    return kfrag, nil
+}
+
+// Splits the re-encryption key from delegatingSK to receivingPK into n KFrags,
+// any threshold of which are needed to re-encrypt a Capsule for receivingPK.
+//
+// Every KFrag is signed by the signer.
+// The KFrags must be freed by the calling function.
+func GenerateKFrags(delegatingSK *PrivateKey, receivingPK *PublicKey, signer *Signer,
+        threshold, n int) ([]*KFrag, error) {
+    if threshold <= 0 || threshold > n {
+        return nil, errors.New("The threshold must be between 1 and the number of kfrags")
+    }
+    params := delegatingSK.Params
+    curve := params.Curve
+
+    if !params.Equals(receivingPK.Params) {
+        return nil, errors.New("The delegating and receiving keys do not share the same parameters")
+    }
+
+    // The precursor is used as an ephemeral public key in a DH key exchange,
+    // and the resulting shared secret d makes Umbral non-interactive.
+    privNI, err := math.GenRandModBN(curve)
+    if err != nil {
+        return nil, err
+    }
+    defer privNI.Free()
+
+    ni, err := newPoint(curve)
+    if err != nil {
+        return nil, err
+    }
+    defer ni.Free()
+
+    err = ni.Mul(params.G, privNI)
+    if err != nil {
+        return nil, err
+    }
+
+    dhNI, err := newPoint(curve)
+    if err != nil {
+        return nil, err
+    }
+    defer dhNI.Free()
+
+    err = dhNI.Mul(receivingPK.PointKey, privNI)
+    if err != nil {
+        return nil, err
+    }
+
+    d, err := math.HashItemsToModBN(params, nil, ni, receivingPK.PointKey, dhNI)
+    if err != nil {
+        return nil, err
+    }
+    defer d.Free()
+
+    // The polynomial f(x) of degree threshold - 1 with f(0) = a / d.
+    coeff0 := newModBN(curve)
+    defer coeff0.Free()
+
+    err = coeff0.Div(delegatingSK.BNKey, d)
+    if err != nil {
+        return nil, err
+    }
+
+    poly, err := math.GenRandPolynomial(coeff0, threshold - 1)
+    if err != nil {
+        return nil, err
+    }
+    defer poly.Free()
+
+    // The x coordinate point is used as an ephemeral public key in a DH key
+    // exchange, and the resulting shared secret prevents the reconstruction
+    // of the re-encryption key without the receiver.
+    privXCoord, err := math.GenRandModBN(curve)
+    if err != nil {
+        return nil, err
+    }
+    defer privXCoord.Free()
+
+    xcoord, err := newPoint(curve)
+    if err != nil {
+        return nil, err
+    }
+    defer xcoord.Free()
+
+    err = xcoord.Mul(params.G, privXCoord)
+    if err != nil {
+        return nil, err
+    }
+
+    dhXCoord, err := newPoint(curve)
+    if err != nil {
+        return nil, err
+    }
+    defer dhXCoord.Free()
+
+    err = dhXCoord.Mul(receivingPK.PointKey, privXCoord)
+    if err != nil {
+        return nil, err
+    }
+
+    hashedDHTuple, err := hashDHTuple(params, xcoord, receivingPK.PointKey, dhXCoord)
+    if err != nil {
+        return nil, err
+    }
+
+    kfrags := make([]*KFrag, 0, n)
+    for i := 0; i < n; i++ {
+        kfrag, err := newKFrag(delegatingSK.GetPublicKey(), receivingPK, signer,
+            poly, hashedDHTuple, ni, xcoord)
+        if err != nil {
+            for _, kfrag := range kfrags {
+                kfrag.Free()
+            }
+            return nil, err
+        }
+        kfrags = append(kfrags, kfrag)
+    }
+    return kfrags, nil
+}
+
+// Returns a KFrag with a random ID holding the share of the polynomial
+// at the x coordinate derived from that ID.
+func newKFrag(delegatingPK, receivingPK *PublicKey, signer *Signer, poly *math.Polynomial,
+        hashedDHTuple []byte, ni, xcoord *math.Point) (*KFrag, error) {
+    params := delegatingPK.Params
+    curve := params.Curve
+
+    id := make([]byte, math.ExpectedBytesLength(curve))
+    _, err := rand.Read(id)
+    if err != nil {
+        return nil, err
+    }
+
+    shareX, err := math.HashItemsToModBN(params, nil, id, hashedDHTuple)
+    if err != nil {
+        return nil, err
+    }
+    defer shareX.Free()
+
+    kfrag := &KFrag{ID: id}
+
+    kfrag.BNKey, err = poly.Eval(shareX)
+    if err != nil {
+        return nil, err
+    }
+
+    kfrag.PointCommitment, err = newPoint(curve)
+    if err != nil {
+        kfrag.Free()
+        return nil, err
+    }
+
+    err = kfrag.PointCommitment.Mul(params.U, kfrag.BNKey)
+    if err != nil {
+        kfrag.Free()
+        return nil, err
+    }
+
+    kfrag.PointNonInteractive, err = ni.Copy()
+    if err != nil {
+        kfrag.Free()
+        return nil, err
+    }
+
+    kfrag.PointXCoord, err = xcoord.Copy()
+    if err != nil {
+        kfrag.Free()
+        return nil, err
+    }
+
+    message, err := kfrag.validityMessage(delegatingPK, receivingPK)
+    if err != nil {
+        kfrag.Free()
+        return nil, err
+    }
+
+    kfrag.Signature, err = signer.Sign(message)
+    if err != nil {
+        kfrag.Free()
+        return nil, err
+    }
+    return kfrag, nil
+}
+
+// Returns the KFrag serialized as
+// id || rk || precursor || commitment || xcoord || signature.
+func (m *KFrag) ToBytes() ([]byte, error) {
+    key, err := m.BNKey.Bytes()
+    if err != nil {
+        return nil, err
+    }
+
+    points, err := pointsToBytes(m.PointNonInteractive, m.PointCommitment, m.PointXCoord)
+    if err != nil {
+        return nil, err
+    }
+
+    signature, err := m.Signature.ToBytes()
+    if err != nil {
+        return nil, err
+    }
+
+    data := append([]byte{}, m.ID...)
+    data = append(data, key...)
+    data = append(data, points...)
+    return append(data, signature...), nil
+}
+
+// KFrag.Verify() checks that the commitment of the KFrag is U^rk and
+// that the KFrag was signed by signingPK for the delegation
+// from delegatingPK to receivingPK.
+//
+// Verify will return false if the KFrag is forged and should not be used.
+func (m *KFrag) Verify(signingPK, delegatingPK, receivingPK *PublicKey) (bool, error) {
+    params := delegatingPK.Params
+
+    commitment, err := newPoint(params.Curve)
+    if err != nil {
+        return false, err
+    }
+    defer commitment.Free()
+
+    err = commitment.Mul(params.U, m.BNKey)
+    if err != nil {
+        return false, err
+    }
+
+    correctCommitment, err := commitment.Equals(m.PointCommitment)
+    if err != nil {
+        return false, err
+    }
+
+    message, err := m.validityMessage(delegatingPK, receivingPK)
+    if err != nil {
+        return false, err
+    }
+
+    validSignature, err := m.Signature.Verify(message, signingPK)
+    if err != nil {
+        return false, err
+    }
+
+    return correctCommitment && validSignature, nil
+}
+
+// Returns the message signed by the delegator for this KFrag:
+// id || delegatingPK || receivingPK || commitment || precursor || xcoord.
+func (m *KFrag) validityMessage(delegatingPK, receivingPK *PublicKey) ([]byte, error) {
+    return kfragValidityMessage(m.ID, delegatingPK, receivingPK,
+        m.PointCommitment, m.PointNonInteractive, m.PointXCoord)
+}
+
+func kfragValidityMessage(id []byte, delegatingPK, receivingPK *PublicKey,
+        commitment, ni, xcoord *math.Point) ([]byte, error) {
+    points, err := pointsToBytes(delegatingPK.PointKey, receivingPK.PointKey,
+        commitment, ni, xcoord)
+    if err != nil {
+        return nil, err
+    }
+    return append(append([]byte{}, id...), points...), nil
+}
+
+// Returns the digest of the DH tuple (xcoord, pubKey, dh)
+// from which the x coordinates of the shares are derived.
+func hashDHTuple(params *math.UmbralParameters, xcoord, pubKey, dh *math.Point) ([]byte, error) {
+    data, err := pointsToBytes(xcoord, pubKey, dh)
+    if err != nil {
+        return nil, err
+    }
+    return params.Hash.Sum(data), nil
+}
+
+func (m *KFrag) Free() {
+    m.BNKey.Free()
+    if m.PointNonInteractive != nil {
+        m.PointNonInteractive.Free()
+    }
+    if m.PointCommitment != nil {
+        m.PointCommitment.Free()
+    }
+    if m.PointXCoord != nil {
+        m.PointXCoord.Free()
+    }
+    if m.Signature != nil {
+        m.Signature.Free()
+    }
+}
@@ -0,0 +1,239 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package umbral_test
+
+import (
+    "bytes"
+    "testing"
+    "encoding/json"
+    "encoding/hex"
+    "io/ioutil"
+    "github.com/nucypher/goUmbral/math"
+    "github.com/nucypher/goUmbral/openssl"
+    "github.com/nucypher/goUmbral/umbral"
+)
+
+func TestGenerateKFrags(t *testing.T) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Error(err)
+    }
+    defer curve.Free()
+
+    params, err := math.NewUmbralParameters(curve)
+    if err != nil {
+        t.Error(err)
+    }
+
+    delegatingKey, err := umbral.GenPrivateKey(params)
+    if err != nil {
+        t.Error(err)
+    }
+    defer delegatingKey.Free()
+
+    receivingKey, err := umbral.GenPrivateKey(params)
+    if err != nil {
+        t.Error(err)
+    }
+    defer receivingKey.Free()
+
+    signingKey, err := umbral.GenPrivateKey(params)
+    if err != nil {
+        t.Error(err)
+    }
+    defer signingKey.Free()
+
+    signer := umbral.NewSigner(signingKey)
+
+    t.Run("threshold=6, n=10", func(t *testing.T) {
+        kfrags, err := umbral.GenerateKFrags(delegatingKey, receivingKey.GetPublicKey(),
+            signer, 6, 10)
+        if err != nil {
+            t.Error(err)
+        }
+
+        if len(kfrags) != 10 {
+            t.Error("Expected 10 kfrags, got:", len(kfrags))
+        }
+
+        for i, kfrag := range kfrags {
+            defer kfrag.Free()
+
+            commitment, err := math.NewPoint(nil, curve)
+            if err != nil {
+                t.Error(err)
+            }
+            defer commitment.Free()
+
+            err = commitment.Mul(params.U, kfrag.BNKey)
+            if err != nil {
+                t.Error(err)
+            }
+
+            equal, err := commitment.Equals(kfrag.PointCommitment)
+            if err != nil {
+                t.Error(err)
+            }
+            if !equal {
+                t.Error("The commitment of the kfrag was not U^rk")
+            }
+
+            // The precursor is shared by all the kfrags.
+            equal, err = kfrag.PointNonInteractive.Equals(kfrags[0].PointNonInteractive)
+            if err != nil {
+                t.Error(err)
+            }
+            if !equal {
+                t.Error("The kfrags do not share the same precursor")
+            }
+
+            valid, err := kfrag.Verify(signer.GetPublicKey(),
+                delegatingKey.GetPublicKey(), receivingKey.GetPublicKey())
+            if err != nil {
+                t.Error(err)
+            }
+            if !valid {
+                t.Error("A generated kfrag did not verify")
+            }
+
+            for _, other := range kfrags[:i] {
+                if bytes.Equal(kfrag.ID, other.ID) {
+                    t.Error("Two kfrags share the same id")
+                }
+            }
+        }
+    })
+    t.Run("invalid threshold", func(t *testing.T) {
+        _, err := umbral.GenerateKFrags(delegatingKey, receivingKey.GetPublicKey(),
+            signer, 0, 10)
+        if err == nil {
+            t.Error("A threshold of 0 should have returned an error")
+        }
+
+        _, err = umbral.GenerateKFrags(delegatingKey, receivingKey.GetPublicKey(),
+            signer, 11, 10)
+        if err == nil {
+            t.Error("A threshold larger than n should have returned an error")
+        }
+    })
+}
+
+type KFragVectors struct {
+    VerifyingKey string `json:"verifying_key"`
+    DelegatingKey string `json:"delegating_key"`
+    ReceivingKey string `json:"receiving_key"`
+    Vectors []KFragVector `json:"vectors"`
+}
+
+type KFragVector struct {
+    KFrag string `json:"kfrag"`
+}
+
+func TestKFragVectors(t *testing.T) {
+    data, err := ioutil.ReadFile("../vectors/vectors_kfrags.json")
+    if err != nil {
+        t.Error(err)
+    }
+
+    var vectors KFragVectors
+    err = json.Unmarshal(data, &vectors)
+    if err != nil {
+        t.Error(err)
+    }
+
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Error(err)
+    }
+    defer curve.Free()
+
+    params, err := math.NewUmbralParameters(curve)
+    if err != nil {
+        t.Error(err)
+    }
+
+    keys := make([]*umbral.PublicKey, 3)
+    for i, k := range []string{vectors.VerifyingKey, vectors.DelegatingKey, vectors.ReceivingKey} {
+        keyBytes, err := hex.DecodeString(k)
+        if err != nil {
+            t.Error(err)
+        }
+
+        keys[i], err = umbral.BytesToPublicKey(keyBytes, params)
+        if err != nil {
+            t.Error(err)
+        }
+        defer keys[i].Free()
+    }
+    verifyingKey, delegatingKey, receivingKey := keys[0], keys[1], keys[2]
+
+    for _, k := range vectors.Vectors {
+        kfragBytes, err := hex.DecodeString(k.KFrag)
+        if err != nil {
+            t.Error(err)
+        }
+
+        kfrag, err := umbral.BytesToKFrag(kfragBytes, params)
+        if err != nil {
+            t.Error(err)
+        }
+        defer kfrag.Free()
+
+        result, err := kfrag.ToBytes()
+        if err != nil {
+            t.Error(err)
+        }
+        if !bytes.Equal(kfragBytes, result) {
+            t.Error("The kfrag did not serialize to the vector:", k.KFrag)
+        }
+
+        valid, err := kfrag.Verify(verifyingKey, delegatingKey, receivingKey)
+        if err != nil {
+            t.Error(err)
+        }
+        if !valid {
+            t.Error("The kfrag vector did not verify:", k.KFrag)
+        }
+
+        // Swapping the delegating and receiving keys must invalidate the signature.
+        valid, err = kfrag.Verify(verifyingKey, receivingKey, delegatingKey)
+        if err != nil {
+            t.Error(err)
+        }
+        if valid {
+            t.Error("The kfrag verified under the wrong keys:", k.KFrag)
+        }
+
+        // Tampering with the re-encryption key must invalidate the commitment.
+        forged := append([]byte{}, kfragBytes...)
+        forged[63] ^= 1
+
+        forgedKFrag, err := umbral.BytesToKFrag(forged, params)
+        if err != nil {
+            t.Error(err)
+        }
+        defer forgedKFrag.Free()
+
+        valid, err = forgedKFrag.Verify(verifyingKey, delegatingKey, receivingKey)
+        if err != nil {
+            t.Error(err)
+        }
+        if valid {
+            t.Error("A forged kfrag was verified:", k.KFrag)
+        }
+    }
+}
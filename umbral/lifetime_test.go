@@ -0,0 +1,137 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package umbral_test
+
+import (
+    "bytes"
+    "runtime"
+    "runtime/debug"
+    "testing"
+    "time"
+    "github.com/nucypher/goUmbral/math"
+    "github.com/nucypher/goUmbral/openssl"
+    "github.com/nucypher/goUmbral/umbral"
+)
+
+func TestReencryptionDoesNotLeak(t *testing.T) {
+    if !openssl.LeakCheck {
+        t.Skip("Build with -tags leakcheck to count the allocations")
+    }
+
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer curve.Free()
+
+    params, err := math.NewUmbralParameters(curve)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    runtime.GC()
+    time.Sleep(10 * time.Millisecond)
+    bns, points := openssl.LiveBigNums(), openssl.LiveECPoints()
+
+    // No finalizer runs, so that only the explicit Frees count.
+    defer debug.SetGCPercent(debug.SetGCPercent(-1))
+    reencryptAndFree(t, params)
+
+    afterBNs, afterPoints := openssl.LiveBigNums(), openssl.LiveECPoints()
+    if afterBNs != bns || afterPoints != points {
+        t.Error("Leaked", afterBNs - bns, "BIGNUMs and", afterPoints - points, "EC_POINTs")
+    }
+}
+
+// Runs a whole re-encryption, including its error paths,
+// and frees every object it makes.
+func reencryptAndFree(t *testing.T, params *math.UmbralParameters) {
+    delegatingKey, err := umbral.GenPrivateKey(params)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer delegatingKey.Free()
+
+    receivingKey, err := umbral.GenPrivateKey(params)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer receivingKey.Free()
+
+    signingKey, err := umbral.GenPrivateKey(params)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer signingKey.Free()
+
+    signer := umbral.NewSigner(signingKey)
+
+    plaintext := []byte("peace at dawn")
+    ciphertext, capsule, err := umbral.Encrypt(delegatingKey.GetPublicKey(), plaintext)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer capsule.Free()
+
+    kfrags, err := umbral.GenerateKFrags(delegatingKey, receivingKey.GetPublicKey(),
+        signer, 2, 3)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    cfrags := make([]*umbral.CFrag, len(kfrags))
+    for i, kfrag := range kfrags {
+        defer kfrag.Free()
+
+        cfrags[i], err = umbral.Reencrypt(kfrag, capsule, true, nil)
+        if err != nil {
+            t.Fatal(err)
+        }
+        defer cfrags[i].Free()
+    }
+
+    err = capsule.WithCorrectnessKeys(delegatingKey.GetPublicKey(),
+        receivingKey.GetPublicKey(), signer.GetPublicKey())
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    for _, cfrag := range cfrags[:2] {
+        err = capsule.AttachCFrag(cfrag)
+        if err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    cleartext, err := umbral.Decrypt(receivingKey, capsule, ciphertext)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !bytes.Equal(plaintext, cleartext) {
+        t.Error("The decrypted data was not equal to the plaintext")
+    }
+
+    _, err = umbral.DecryptReencrypted(delegatingKey, capsule, cfrags[:2], ciphertext)
+    if err == nil {
+        t.Error("The wrong receiving key decrypted the ciphertext")
+    }
+
+    _, err = umbral.DecryptReencrypted(receivingKey, capsule, cfrags[:1], ciphertext)
+    if err == nil {
+        t.Error("Too few cfrags decrypted the ciphertext")
+    }
+}
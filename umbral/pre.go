@@ -0,0 +1,509 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package umbral
+
+import (
+    "errors"
+    "github.com/nucypher/goUmbral/math"
+)
+
+// Generates a symmetric key and its associated Capsule
+// for the given public key.
+//
+// The Capsule must be freed by the calling function.
+func Encapsulate(pubKey *PublicKey) ([]byte, *Capsule, error) {
+    params := pubKey.Params
+
+    privR, err := math.GenRandModBN(params.Curve)
+    if err != nil {
+        return nil, nil, err
+    }
+    defer privR.Free()
+
+    privU, err := math.GenRandModBN(params.Curve)
+    if err != nil {
+        return nil, nil, err
+    }
+    defer privU.Free()
+
+    pubR, err := newPoint(params.Curve)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    pubU, err := newPoint(params.Curve)
+    if err != nil {
+        pubR.Free()
+        return nil, nil, err
+    }
+
+    capsule := &Capsule{Params: params, PointE: pubR, PointV: pubU,
+        BNSig: newModBN(params.Curve)}
+
+    key, err := encapsulate(pubKey, privR, privU, capsule)
+    if err != nil {
+        capsule.Free()
+        return nil, nil, err
+    }
+    return key, capsule, nil
+}
+
+// Sets E = g^r, V = g^u and s = u + r * h(E, V) in the capsule,
+// and returns the key derived from pk^(r + u).
+func encapsulate(pubKey *PublicKey, privR, privU *math.ModBigNum, capsule *Capsule) ([]byte, error) {
+    params := pubKey.Params
+
+    err := capsule.PointE.Mul(params.G, privR)
+    if err != nil {
+        return nil, err
+    }
+
+    err = capsule.PointV.Mul(params.G, privU)
+    if err != nil {
+        return nil, err
+    }
+
+    h, err := math.HashItemsToModBN(params, nil, capsule.PointE, capsule.PointV)
+    if err != nil {
+        return nil, err
+    }
+    defer h.Free()
+
+    err = capsule.BNSig.Mul(privR, h)
+    if err != nil {
+        return nil, err
+    }
+
+    err = capsule.BNSig.Add(privU, capsule.BNSig)
+    if err != nil {
+        return nil, err
+    }
+
+    sum := newModBN(params.Curve)
+    defer sum.Free()
+
+    err = sum.Add(privR, privU)
+    if err != nil {
+        return nil, err
+    }
+
+    sharedKey, err := newPoint(params.Curve)
+    if err != nil {
+        return nil, err
+    }
+    defer sharedKey.Free()
+
+    err = sharedKey.Mul(pubKey.PointKey, sum)
+    if err != nil {
+        return nil, err
+    }
+
+    return kdf(sharedKey, params.Hash, DEMKeySize)
+}
+
+// Derives the symmetric key of the Capsule with the private key
+// of its original recipient.
+//
+// Decapsulate will return an AuthenticationError if the Capsule does not verify.
+func Decapsulate(privKey *PrivateKey, capsule *Capsule) ([]byte, error) {
+    valid, err := capsule.Verify()
+    if err != nil {
+        return nil, err
+    }
+    if !valid {
+        return nil, &AuthenticationError{"The capsule is not valid"}
+    }
+
+    params := capsule.Params
+
+    sharedKey, err := newPoint(params.Curve)
+    if err != nil {
+        return nil, err
+    }
+    defer sharedKey.Free()
+
+    err = sharedKey.Add(capsule.PointE, capsule.PointV)
+    if err != nil {
+        return nil, err
+    }
+
+    err = sharedKey.Mul(sharedKey, privKey.BNKey)
+    if err != nil {
+        return nil, err
+    }
+
+    return kdf(sharedKey, params.Hash, DEMKeySize)
+}
+
+// Encrypts the plaintext for the given public key.
+//
+// Returns the ciphertext and the Capsule needed to decrypt it.
+// The Capsule must be freed by the calling function.
+func Encrypt(pubKey *PublicKey, plaintext []byte) ([]byte, *Capsule, error) {
+    key, capsule, err := Encapsulate(pubKey)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    ciphertext, err := encryptWithCapsule(key, capsule, plaintext)
+    if err != nil {
+        capsule.Free()
+        return nil, nil, err
+    }
+    return ciphertext, capsule, nil
+}
+
+func encryptWithCapsule(key []byte, capsule *Capsule, plaintext []byte) ([]byte, error) {
+    dem, err := NewDEM(key)
+    if err != nil {
+        return nil, err
+    }
+
+    capsuleBytes, err := capsule.ToBytes()
+    if err != nil {
+        return nil, err
+    }
+
+    // The Capsule is authenticated along with the ciphertext.
+    return dem.Encrypt(plaintext, capsuleBytes)
+}
+
+// Decrypts the ciphertext with the private key of its original recipient,
+// or with the receiving key if CFrags are attached to the Capsule.
+//
+// Decrypt will return an AuthenticationError if either the ciphertext
+// or the Capsule have been tampered with, and ErrNotEnoughCFrags if fewer
+// CFrags than the threshold are attached.
+func Decrypt(privKey *PrivateKey, capsule *Capsule, ciphertext []byte) ([]byte, error) {
+    if len(capsule.cfrags) > 0 {
+        equal, err := capsule.receivingPK.Equals(privKey.GetPublicKey())
+        if err != nil {
+            return nil, err
+        }
+        if !equal {
+            return nil, errors.New("The private key is not the receiving key of the capsule")
+        }
+        // The attached CFrags have already been verified.
+        return DecryptReencrypted(privKey, capsule, capsule.cfrags, ciphertext)
+    }
+
+    key, err := Decapsulate(privKey, capsule)
+    if err != nil {
+        return nil, err
+    }
+    return decryptWithCapsule(key, capsule, ciphertext)
+}
+
+func decryptWithCapsule(key []byte, capsule *Capsule, ciphertext []byte) ([]byte, error) {
+    dem, err := NewDEM(key)
+    if err != nil {
+        return nil, err
+    }
+
+    capsuleBytes, err := capsule.ToBytes()
+    if err != nil {
+        return nil, err
+    }
+    return dem.Decrypt(ciphertext, capsuleBytes)
+}
+
+// Re-encrypts the Capsule with the KFrag, computing E1 = E^rk and V1 = V^rk.
+//
+// If provideProof is set, a CorrectnessProof binding the optional metadata
+// is attached to the CFrag so that it can be checked with CFrag.Verify().
+//
+// Reencrypt will return an AuthenticationError if the Capsule does not verify.
+// The CFrag must be freed by the calling function.
+func Reencrypt(kfrag *KFrag, capsule *Capsule, provideProof bool, metadata []byte) (*CFrag, error) {
+    valid, err := capsule.Verify()
+    if err != nil {
+        return nil, err
+    }
+    if !valid {
+        return nil, &AuthenticationError{"The capsule is not valid"}
+    }
+
+    curve := capsule.Params.Curve
+    cfrag := &CFrag{KFragID: append([]byte{}, kfrag.ID...)}
+
+    cfrag.PointE1, err = newPoint(curve)
+    if err != nil {
+        return nil, err
+    }
+
+    err = cfrag.PointE1.Mul(capsule.PointE, kfrag.BNKey)
+    if err != nil {
+        cfrag.Free()
+        return nil, err
+    }
+
+    cfrag.PointV1, err = newPoint(curve)
+    if err != nil {
+        cfrag.Free()
+        return nil, err
+    }
+
+    err = cfrag.PointV1.Mul(capsule.PointV, kfrag.BNKey)
+    if err != nil {
+        cfrag.Free()
+        return nil, err
+    }
+
+    cfrag.PointNonInteractive, err = kfrag.PointNonInteractive.Copy()
+    if err != nil {
+        cfrag.Free()
+        return nil, err
+    }
+
+    cfrag.PointXCoord, err = kfrag.PointXCoord.Copy()
+    if err != nil {
+        cfrag.Free()
+        return nil, err
+    }
+
+    if provideProof {
+        err = proveCorrectness(cfrag, kfrag, capsule, metadata)
+        if err != nil {
+            cfrag.Free()
+            return nil, err
+        }
+    }
+    return cfrag, nil
+}
+
+// Decrypts the ciphertext of a Capsule re-encrypted for the receiving key,
+// combining the CFrags produced by at least threshold distinct KFrags.
+//
+// DecryptReencrypted will return an error if two CFrags share the same KFrag ID
+// or come from different delegations, and an AuthenticationError if the
+// ciphertext, the Capsule or the CFrags fail to authenticate, which is also
+// the case when fewer than threshold CFrags are provided.
+//
+// If the CFrags are those returned by Capsule.AttachedCFrags(), and so were
+// verified against its correctness keys, and receivingSK matches its
+// receiving key, then ErrNotEnoughCFrags is returned instead when there are too few of them.
+func DecryptReencrypted(receivingSK *PrivateKey, capsule *Capsule, cfrags []*CFrag,
+        ciphertext []byte) ([]byte, error) {
+    key, err := decapsulateReencrypted(receivingSK, capsule, cfrags)
+    if err != nil {
+        return nil, err
+    }
+
+    plaintext, err := decryptWithCapsule(key, capsule, ciphertext)
+    if err != nil {
+        if _, ok := err.(*AuthenticationError); ok {
+            return nil, &AuthenticationError{"Decryption with the cfrags failed: " +
+                "fewer cfrags than the threshold were provided, " +
+                "or the ciphertext or the cfrags have been tampered with"}
+        }
+        return nil, err
+    }
+    return plaintext, nil
+}
+
+// Derives the symmetric key of the Capsule from its CFrags with the receiving key.
+func decapsulateReencrypted(receivingSK *PrivateKey, capsule *Capsule, cfrags []*CFrag) ([]byte, error) {
+    if len(cfrags) == 0 {
+        return nil, errors.New("At least one cfrag is needed to decrypt")
+    }
+
+    err := checkCFrags(cfrags)
+    if err != nil {
+        return nil, err
+    }
+
+    valid, err := capsule.Verify()
+    if err != nil {
+        return nil, err
+    }
+    if !valid {
+        return nil, &AuthenticationError{"The capsule is not valid"}
+    }
+
+    params := capsule.Params
+    curve := params.Curve
+    pubKey := receivingSK.GetPublicKey().PointKey
+    ni := cfrags[0].PointNonInteractive
+    xcoord := cfrags[0].PointXCoord
+
+    // d = H(ni, b, ni^b)
+    dhNI, err := newPoint(curve)
+    if err != nil {
+        return nil, err
+    }
+    defer dhNI.Free()
+
+    err = dhNI.Mul(ni, receivingSK.BNKey)
+    if err != nil {
+        return nil, err
+    }
+
+    d, err := math.HashItemsToModBN(params, nil, ni, pubKey, dhNI)
+    if err != nil {
+        return nil, err
+    }
+    defer d.Free()
+
+    dhXCoord, err := newPoint(curve)
+    if err != nil {
+        return nil, err
+    }
+    defer dhXCoord.Free()
+
+    err = dhXCoord.Mul(xcoord, receivingSK.BNKey)
+    if err != nil {
+        return nil, err
+    }
+
+    hashedDHTuple, err := hashDHTuple(params, xcoord, pubKey, dhXCoord)
+    if err != nil {
+        return nil, err
+    }
+
+    ePrime, vPrime, err := combineCFrags(cfrags, hashedDHTuple, params)
+    if err != nil {
+        return nil, err
+    }
+    defer ePrime.Free()
+    defer vPrime.Free()
+
+    if capsule.delegatingPK != nil {
+        valid, err = checkReencryptedCapsule(capsule, ePrime, vPrime, d)
+        if err != nil {
+            return nil, err
+        }
+        if !valid {
+            // Correct CFrags only fail to combine if there are too few of them.
+            attached, err := attachedForReceiver(capsule, receivingSK, cfrags)
+            if err != nil {
+                return nil, err
+            }
+            if attached {
+                return nil, ErrNotEnoughCFrags
+            }
+            return nil, &AuthenticationError{"The re-encrypted capsule is not valid"}
+        }
+    }
+
+    // The shared key is (E' + V')^d.
+    sharedKey, err := newPoint(curve)
+    if err != nil {
+        return nil, err
+    }
+    defer sharedKey.Free()
+
+    err = sharedKey.Add(ePrime, vPrime)
+    if err != nil {
+        return nil, err
+    }
+
+    err = sharedKey.Mul(sharedKey, d)
+    if err != nil {
+        return nil, err
+    }
+
+    return kdf(sharedKey, params.Hash, DEMKeySize)
+}
+
+// Returns whether the CFrags are all attached to the Capsule, and the
+// receiving key is the one bound to it.
+func attachedForReceiver(capsule *Capsule, receivingSK *PrivateKey, cfrags []*CFrag) (bool, error) {
+    if capsule.receivingPK == nil {
+        return false, nil
+    }
+
+    equal, err := capsule.receivingPK.Equals(receivingSK.GetPublicKey())
+    if err != nil || !equal {
+        return false, err
+    }
+
+    for _, cfrag := range cfrags {
+        attached := false
+        for _, other := range capsule.cfrags {
+            if cfrag == other {
+                attached = true
+                break
+            }
+        }
+        if !attached {
+            return false, nil
+        }
+    }
+    return true, nil
+}
+
+// Checks that pk_a^(s / d) == E'^h * V' where pk_a is the delegating key
+// of the Capsule and h is the hash of E and V.
+func checkReencryptedCapsule(capsule *Capsule, ePrime, vPrime *math.Point,
+        d *math.ModBigNum) (bool, error) {
+    params := capsule.Params
+
+    h, err := math.HashItemsToModBN(params, nil, capsule.PointE, capsule.PointV)
+    if err != nil {
+        return false, err
+    }
+    defer h.Free()
+
+    exp := newModBN(params.Curve)
+    defer exp.Free()
+
+    err = exp.Div(capsule.BNSig, d)
+    if err != nil {
+        return false, err
+    }
+
+    return checkProofEquation(exp, capsule.delegatingPK.PointKey, vPrime, h, ePrime)
+}
+
+// Returns E' and V', the combination of the E1 and V1 Points of the CFrags
+// weighted by their Lagrange coefficients at zero.
+func combineCFrags(cfrags []*CFrag, hashedDHTuple []byte,
+        params *math.UmbralParameters) (*math.Point, *math.Point, error) {
+    xs := make([]*math.ModBigNum, len(cfrags))
+    defer func() {
+        for _, x := range xs {
+            if x != nil {
+                x.Free()
+            }
+        }
+    }()
+
+    e1s := make([]*math.Point, len(cfrags))
+    v1s := make([]*math.Point, len(cfrags))
+
+    var err error
+    for i, cfrag := range cfrags {
+        xs[i], err = math.HashItemsToModBN(params, nil, cfrag.KFragID, hashedDHTuple)
+        if err != nil {
+            return nil, nil, err
+        }
+        e1s[i] = cfrag.PointE1
+        v1s[i] = cfrag.PointV1
+    }
+
+    ePrime, err := math.InterpolateInExponent(xs, e1s)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    vPrime, err := math.InterpolateInExponent(xs, v1s)
+    if err != nil {
+        ePrime.Free()
+        return nil, nil, err
+    }
+    return ePrime, vPrime, nil
+}
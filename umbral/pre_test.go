@@ -0,0 +1,619 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package umbral_test
+
+import (
+    "bytes"
+    "errors"
+    "sync"
+    "testing"
+    "github.com/nucypher/goUmbral/math"
+    "github.com/nucypher/goUmbral/openssl"
+    "github.com/nucypher/goUmbral/umbral"
+)
+
+func TestEncapsulateDecapsulate(t *testing.T) {
+    secp256k1, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Error(err)
+    }
+    defer secp256k1.Free()
+
+    secp256r1, err := openssl.NewCurve(openssl.SECP256R1)
+    if err != nil {
+        t.Error(err)
+    }
+    defer secp256r1.Free()
+
+    secp384r1, err := openssl.NewCurve(openssl.SECP384R1)
+    if err != nil {
+        t.Error(err)
+    }
+    defer secp384r1.Free()
+
+    for _, curve := range []*openssl.Curve{secp256k1, secp256r1, secp384r1} {
+        params, err := math.NewUmbralParameters(curve)
+        if err != nil {
+            t.Error(err)
+        }
+
+        privKey, err := umbral.GenPrivateKey(params)
+        if err != nil {
+            t.Error(err)
+        }
+        defer privKey.Free()
+
+        key, capsule, err := umbral.Encapsulate(privKey.GetPublicKey())
+        if err != nil {
+            t.Error(err)
+        }
+        defer capsule.Free()
+
+        if len(key) != umbral.DEMKeySize {
+            t.Error("The symmetric key does not have the right size:", len(key))
+        }
+
+        valid, err := capsule.Verify()
+        if err != nil {
+            t.Error(err)
+        }
+        if !valid {
+            t.Error("A freshly generated capsule did not verify")
+        }
+
+        decKey, err := umbral.Decapsulate(privKey, capsule)
+        if err != nil {
+            t.Error(err)
+        }
+
+        if !bytes.Equal(key, decKey) {
+            t.Error("The decapsulated key was not equal to the encapsulated key")
+        }
+
+        otherKey, err := umbral.GenPrivateKey(params)
+        if err != nil {
+            t.Error(err)
+        }
+        defer otherKey.Free()
+
+        wrongKey, err := umbral.Decapsulate(otherKey, capsule)
+        if err != nil {
+            t.Error(err)
+        }
+
+        if bytes.Equal(key, wrongKey) {
+            t.Error("A different private key decapsulated the same key")
+        }
+    }
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Error(err)
+    }
+    defer curve.Free()
+
+    params, err := math.NewUmbralParameters(curve)
+    if err != nil {
+        t.Error(err)
+    }
+
+    privKey, err := umbral.GenPrivateKey(params)
+    if err != nil {
+        t.Error(err)
+    }
+    defer privKey.Free()
+
+    plaintext := []byte("peace at dawn")
+
+    ciphertext, capsule, err := umbral.Encrypt(privKey.GetPublicKey(), plaintext)
+    if err != nil {
+        t.Error(err)
+    }
+    defer capsule.Free()
+
+    t.Run("valid", func(t *testing.T) {
+        cleartext, err := umbral.Decrypt(privKey, capsule, ciphertext)
+        if err != nil {
+            t.Error(err)
+        }
+        if !bytes.Equal(plaintext, cleartext) {
+            t.Error("The decrypted data was not equal to the plaintext")
+        }
+    })
+    t.Run("tampered ciphertext", func(t *testing.T) {
+        tampered := append([]byte{}, ciphertext...)
+        tampered[len(tampered) - 1] ^= 1
+
+        _, err := umbral.Decrypt(privKey, capsule, tampered)
+        if _, ok := err.(*umbral.AuthenticationError); !ok {
+            t.Error("Expected an AuthenticationError, got:", err)
+        }
+    })
+    t.Run("truncated ciphertext", func(t *testing.T) {
+        _, err := umbral.Decrypt(privKey, capsule, ciphertext[:umbral.DEMNonceSize])
+        if _, ok := err.(*umbral.AuthenticationError); !ok {
+            t.Error("Expected an AuthenticationError, got:", err)
+        }
+    })
+    t.Run("other capsule", func(t *testing.T) {
+        _, otherCapsule, err := umbral.Encrypt(privKey.GetPublicKey(), plaintext)
+        if err != nil {
+            t.Error(err)
+        }
+        defer otherCapsule.Free()
+
+        _, err = umbral.Decrypt(privKey, otherCapsule, ciphertext)
+        if _, ok := err.(*umbral.AuthenticationError); !ok {
+            t.Error("Expected an AuthenticationError, got:", err)
+        }
+    })
+    t.Run("tampered capsule", func(t *testing.T) {
+        capsuleBytes, err := capsule.ToBytes()
+        if err != nil {
+            t.Error(err)
+        }
+        capsuleBytes[len(capsuleBytes) - 1] ^= 1
+
+        tampered, err := umbral.BytesToCapsule(capsuleBytes, params)
+        if err != nil {
+            t.Error(err)
+        }
+        defer tampered.Free()
+
+        _, err = umbral.Decrypt(privKey, tampered, ciphertext)
+        if _, ok := err.(*umbral.AuthenticationError); !ok {
+            t.Error("Expected an AuthenticationError, got:", err)
+        }
+    })
+}
+
+func TestDecryptReencrypted(t *testing.T) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Error(err)
+    }
+    defer curve.Free()
+
+    params, err := math.NewUmbralParameters(curve)
+    if err != nil {
+        t.Error(err)
+    }
+
+    delegatingKey, err := umbral.GenPrivateKey(params)
+    if err != nil {
+        t.Error(err)
+    }
+    defer delegatingKey.Free()
+
+    receivingKey, err := umbral.GenPrivateKey(params)
+    if err != nil {
+        t.Error(err)
+    }
+    defer receivingKey.Free()
+
+    signingKey, err := umbral.GenPrivateKey(params)
+    if err != nil {
+        t.Error(err)
+    }
+    defer signingKey.Free()
+
+    signer := umbral.NewSigner(signingKey)
+
+    plaintext := []byte("peace at dawn")
+
+    ciphertext, capsule, err := umbral.Encrypt(delegatingKey.GetPublicKey(), plaintext)
+    if err != nil {
+        t.Error(err)
+    }
+    defer capsule.Free()
+
+    kfrags, err := umbral.GenerateKFrags(delegatingKey, receivingKey.GetPublicKey(),
+        signer, 3, 5)
+    if err != nil {
+        t.Error(err)
+    }
+
+    cfrags := make([]*umbral.CFrag, len(kfrags))
+    for i, kfrag := range kfrags {
+        defer kfrag.Free()
+
+        cfrags[i], err = umbral.Reencrypt(kfrag, capsule, true, nil)
+        if err != nil {
+            t.Error(err)
+        }
+        defer cfrags[i].Free()
+
+        valid, err := cfrags[i].Verify(capsule, delegatingKey.GetPublicKey(),
+            receivingKey.GetPublicKey(), signer.GetPublicKey())
+        if err != nil {
+            t.Error(err)
+        }
+        if !valid {
+            t.Error("A correct cfrag did not verify")
+        }
+    }
+
+    t.Run("threshold cfrags", func(t *testing.T) {
+        cleartext, err := umbral.DecryptReencrypted(receivingKey, capsule, cfrags[:3], ciphertext)
+        if err != nil {
+            t.Error(err)
+        }
+        if !bytes.Equal(plaintext, cleartext) {
+            t.Error("The decrypted data was not equal to the plaintext")
+        }
+    })
+    t.Run("all cfrags", func(t *testing.T) {
+        cleartext, err := umbral.DecryptReencrypted(receivingKey, capsule, cfrags[1:], ciphertext)
+        if err != nil {
+            t.Error(err)
+        }
+        if !bytes.Equal(plaintext, cleartext) {
+            t.Error("The decrypted data was not equal to the plaintext")
+        }
+    })
+    t.Run("too few cfrags", func(t *testing.T) {
+        _, err := umbral.DecryptReencrypted(receivingKey, capsule, cfrags[:2], ciphertext)
+        if _, ok := err.(*umbral.AuthenticationError); !ok {
+            t.Error("Expected an AuthenticationError, got:", err)
+        }
+    })
+    t.Run("duplicate cfrags", func(t *testing.T) {
+        duplicates := []*umbral.CFrag{cfrags[0], cfrags[1], cfrags[0]}
+        _, err := umbral.DecryptReencrypted(receivingKey, capsule, duplicates, ciphertext)
+        if err == nil {
+            t.Error("Two cfrags with the same id should have returned an error")
+        }
+    })
+    t.Run("no cfrags", func(t *testing.T) {
+        _, err := umbral.DecryptReencrypted(receivingKey, capsule, nil, ciphertext)
+        if err == nil {
+            t.Error("No cfrags should have returned an error")
+        }
+    })
+    t.Run("wrong receiving key", func(t *testing.T) {
+        _, err := umbral.DecryptReencrypted(delegatingKey, capsule, cfrags[:3], ciphertext)
+        if _, ok := err.(*umbral.AuthenticationError); !ok {
+            t.Error("Expected an AuthenticationError, got:", err)
+        }
+    })
+    t.Run("too few attached cfrags", func(t *testing.T) {
+        err := capsule.WithCorrectnessKeys(delegatingKey.GetPublicKey(),
+            receivingKey.GetPublicKey(), signer.GetPublicKey())
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        for _, cfrag := range cfrags[:2] {
+            err = capsule.AttachCFrag(cfrag)
+            if err != nil {
+                t.Fatal(err)
+            }
+        }
+
+        _, err = umbral.DecryptReencrypted(receivingKey, capsule, capsule.AttachedCFrags(), ciphertext)
+        if !errors.Is(err, umbral.ErrNotEnoughCFrags) {
+            t.Error("Got:", err, "Expected:", umbral.ErrNotEnoughCFrags)
+        }
+
+        // The cfrags must be checked against the receiving key of the capsule.
+        _, err = umbral.DecryptReencrypted(delegatingKey, capsule, capsule.AttachedCFrags(), ciphertext)
+        if _, ok := err.(*umbral.AuthenticationError); !ok {
+            t.Error("Expected an AuthenticationError, got:", err)
+        }
+
+        err = capsule.AttachCFrag(cfrags[2])
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        cleartext, err := umbral.DecryptReencrypted(receivingKey, capsule, capsule.AttachedCFrags(), ciphertext)
+        if err != nil {
+            t.Error(err)
+        }
+        if !bytes.Equal(plaintext, cleartext) {
+            t.Error("The decrypted data was not equal to the plaintext")
+        }
+    })
+}
+
+// Proxies re-encrypt concurrently with shared parameters, capsules and kfrags.
+func TestConcurrentReencrypt(t *testing.T) {
+    // Without the tables, the shared keys are not in affine
+    // coordinates internally.
+    t.Run("generic", func(t *testing.T) {
+        testConcurrentReencrypt(t, false)
+    })
+    t.Run("precomputed", func(t *testing.T) {
+        testConcurrentReencrypt(t, true)
+    })
+}
+
+func testConcurrentReencrypt(t *testing.T, precompute bool) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer curve.Free()
+
+    params, err := math.NewUmbralParameters(curve)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer params.Free()
+
+    if precompute {
+        err = params.Precompute()
+        if err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    delegatingKey, err := umbral.GenPrivateKey(params)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer delegatingKey.Free()
+
+    receivingKey, err := umbral.GenPrivateKey(params)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer receivingKey.Free()
+
+    signingKey, err := umbral.GenPrivateKey(params)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer signingKey.Free()
+
+    signer := umbral.NewSigner(signingKey)
+
+    _, capsule, err := umbral.Encrypt(delegatingKey.GetPublicKey(), []byte("peace at dawn"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer capsule.Free()
+
+    kfrags, err := umbral.GenerateKFrags(delegatingKey, receivingKey.GetPublicKey(),
+        signer, 2, 4)
+    if err != nil {
+        t.Fatal(err)
+    }
+    for _, kfrag := range kfrags {
+        defer kfrag.Free()
+    }
+
+    var wg sync.WaitGroup
+    for i := 0; i < 100; i++ {
+        wg.Add(1)
+        go func(kfrag *umbral.KFrag) {
+            defer wg.Done()
+
+            cfrag, err := umbral.Reencrypt(kfrag, capsule, true, nil)
+            if err != nil {
+                t.Error(err)
+                return
+            }
+            defer cfrag.Free()
+
+            valid, err := cfrag.Verify(capsule, delegatingKey.GetPublicKey(),
+                receivingKey.GetPublicKey(), signer.GetPublicKey())
+            if err != nil {
+                t.Error(err)
+            }
+            if !valid {
+                t.Error("A correct cfrag did not verify")
+            }
+        }(kfrags[i % len(kfrags)])
+    }
+    wg.Wait()
+}
+
+func TestDecryptReencryptedWithHash(t *testing.T) {
+    curve, err := openssl.NewCurve(openssl.SECP256R1)
+    if err != nil {
+        t.Error(err)
+    }
+    defer curve.Free()
+
+    for _, hash := range []math.HashFunction{math.SHA256, math.SHA512,
+            math.SHA3_256, math.Keccak256} {
+        params, err := math.NewUmbralParametersWithHash(curve, hash)
+        if err != nil {
+            t.Error(err)
+        }
+
+        delegatingKey, err := umbral.GenPrivateKey(params)
+        if err != nil {
+            t.Error(err)
+        }
+        defer delegatingKey.Free()
+
+        receivingKey, err := umbral.GenPrivateKey(params)
+        if err != nil {
+            t.Error(err)
+        }
+        defer receivingKey.Free()
+
+        signingKey, err := umbral.GenPrivateKey(params)
+        if err != nil {
+            t.Error(err)
+        }
+        defer signingKey.Free()
+
+        signer := umbral.NewSigner(signingKey)
+
+        plaintext := []byte("peace at dawn")
+
+        ciphertext, capsule, err := umbral.Encrypt(delegatingKey.GetPublicKey(), plaintext)
+        if err != nil {
+            t.Error(err)
+        }
+        defer capsule.Free()
+
+        kfrags, err := umbral.GenerateKFrags(delegatingKey, receivingKey.GetPublicKey(),
+            signer, 2, 3)
+        if err != nil {
+            t.Error(err)
+        }
+
+        cfrags := make([]*umbral.CFrag, len(kfrags))
+        for i, kfrag := range kfrags {
+            defer kfrag.Free()
+
+            valid, err := kfrag.Verify(signer.GetPublicKey(), delegatingKey.GetPublicKey(),
+                receivingKey.GetPublicKey())
+            if err != nil {
+                t.Error(err)
+            }
+            if !valid {
+                t.Error("A correct kfrag did not verify with", hash)
+            }
+
+            cfrags[i], err = umbral.Reencrypt(kfrag, capsule, true, nil)
+            if err != nil {
+                t.Error(err)
+            }
+            defer cfrags[i].Free()
+
+            valid, err = cfrags[i].Verify(capsule, delegatingKey.GetPublicKey(),
+                receivingKey.GetPublicKey(), signer.GetPublicKey())
+            if err != nil {
+                t.Error(err)
+            }
+            if !valid {
+                t.Error("A correct cfrag did not verify with", hash)
+            }
+        }
+
+        cleartext, err := umbral.DecryptReencrypted(receivingKey, capsule, cfrags[1:], ciphertext)
+        if err != nil {
+            t.Error(err)
+        }
+        if !bytes.Equal(plaintext, cleartext) {
+            t.Error("The decrypted data was not equal to the plaintext with", hash)
+        }
+    }
+}
+
+func BenchmarkReencrypt(b *testing.B) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        b.Fatal(err)
+    }
+    defer curve.Free()
+
+    params, err := math.NewUmbralParameters(curve)
+    if err != nil {
+        b.Fatal(err)
+    }
+
+    delegatingKey, receivingKey, signer := benchmarkKeys(b, params)
+    defer delegatingKey.Free()
+    defer receivingKey.Free()
+
+    _, capsule, err := umbral.Encrypt(delegatingKey.GetPublicKey(), []byte("peace at dawn"))
+    if err != nil {
+        b.Fatal(err)
+    }
+    defer capsule.Free()
+
+    kfrags, err := umbral.GenerateKFrags(delegatingKey, receivingKey.GetPublicKey(), signer, 1, 1)
+    if err != nil {
+        b.Fatal(err)
+    }
+    defer kfrags[0].Free()
+
+    b.ReportAllocs()
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        cfrag, err := umbral.Reencrypt(kfrags[0], capsule, true, nil)
+        if err != nil {
+            b.Fatal(err)
+        }
+        cfrag.Free()
+    }
+}
+
+func BenchmarkDecryptReencrypted(b *testing.B) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        b.Fatal(err)
+    }
+    defer curve.Free()
+
+    params, err := math.NewUmbralParameters(curve)
+    if err != nil {
+        b.Fatal(err)
+    }
+
+    delegatingKey, receivingKey, signer := benchmarkKeys(b, params)
+    defer delegatingKey.Free()
+    defer receivingKey.Free()
+
+    ciphertext, capsule, err := umbral.Encrypt(delegatingKey.GetPublicKey(), []byte("peace at dawn"))
+    if err != nil {
+        b.Fatal(err)
+    }
+    defer capsule.Free()
+
+    kfrags, err := umbral.GenerateKFrags(delegatingKey, receivingKey.GetPublicKey(), signer, 10, 10)
+    if err != nil {
+        b.Fatal(err)
+    }
+
+    cfrags := make([]*umbral.CFrag, len(kfrags))
+    for i, kfrag := range kfrags {
+        defer kfrag.Free()
+
+        cfrags[i], err = umbral.Reencrypt(kfrag, capsule, true, nil)
+        if err != nil {
+            b.Fatal(err)
+        }
+        defer cfrags[i].Free()
+    }
+
+    b.ReportAllocs()
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        _, err := umbral.DecryptReencrypted(receivingKey, capsule, cfrags, ciphertext)
+        if err != nil {
+            b.Fatal(err)
+        }
+    }
+}
+
+func benchmarkKeys(b *testing.B, params *math.UmbralParameters) (*umbral.PrivateKey,
+        *umbral.PrivateKey, *umbral.Signer) {
+    delegatingKey, err := umbral.GenPrivateKey(params)
+    if err != nil {
+        b.Fatal(err)
+    }
+
+    receivingKey, err := umbral.GenPrivateKey(params)
+    if err != nil {
+        b.Fatal(err)
+    }
+
+    signingKey, err := umbral.GenPrivateKey(params)
+    if err != nil {
+        b.Fatal(err)
+    }
+    return delegatingKey, receivingKey, umbral.NewSigner(signingKey)
+}
@@ -0,0 +1,272 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package umbral
+
+import (
+    "errors"
+    "github.com/nucypher/goUmbral/math"
+)
+
+// Represents a proof that a CFrag was correctly re-encrypted with a KFrag:
+// a Chaum-Pedersen style proof of knowledge of rk such that E1 = E^rk,
+// V1 = V^rk and U1 = U^rk, along with the signature of the KFrag.
+//
+// The metadata is optional and is bound to the proof by its challenge.
+
+type CorrectnessProof struct {
+    PointE2 *math.Point
+    PointV2 *math.Point
+    PointKFragCommitment *math.Point
+    PointKFragPok *math.Point
+    BNSig *math.ModBigNum
+    KFragSignature *Signature
+    Metadata []byte
+}
+
+// Returns the minimum size (in bytes) of a serialized CorrectnessProof,
+// that is, without metadata, given the parameters.
+func CorrectnessProofLength(params *math.UmbralParameters) uint {
+    bnSize := math.ExpectedBytesLength(params.Curve)
+    pointSize := math.PointLength(params.Curve, true)
+    return 4 * pointSize + bnSize + SignatureLength(params.Curve)
+}
+
+// Returns the CorrectnessProof from its serialization
+// e2 || v2 || u1 || u2 || z3 || kfrag signature || metadata.
+func BytesToCorrectnessProof(data []byte, params *math.UmbralParameters) (*CorrectnessProof, error) {
+    if uint(len(data)) < CorrectnessProofLength(params) {
+        return nil, errors.New("The correctness proof is too short")
+    }
+    curve := params.Curve
+    bnSize := int(math.ExpectedBytesLength(curve))
+    pointSize := int(math.PointLength(curve, true))
+
+    proof := &CorrectnessProof{}
+
+    points := make([]*math.Point, 4)
+    var err error
+    for i := range points {
+        points[i], err = math.BytesToPoint(data[:pointSize], curve)
+        if err != nil {
+            for _, point := range points[:i] {
+                point.Free()
+            }
+            return nil, err
+        }
+        data = data[pointSize:]
+    }
+    proof.PointE2 = points[0]
+    proof.PointV2 = points[1]
+    proof.PointKFragCommitment = points[2]
+    proof.PointKFragPok = points[3]
+
+    proof.BNSig, err = math.BytesToModBN(data[:bnSize], curve)
+    if err != nil {
+        proof.Free()
+        return nil, err
+    }
+    data = data[bnSize:]
+
+    sigSize := SignatureLength(curve)
+    proof.KFragSignature, err = BytesToSignature(data[:sigSize], curve)
+    if err != nil {
+        proof.Free()
+        return nil, err
+    }
+    data = data[sigSize:]
+
+    if len(data) > 0 {
+        proof.Metadata = append([]byte{}, data...)
+    }
+    return proof, nil
+}
+
+// Returns the CorrectnessProof serialized as
+// e2 || v2 || u1 || u2 || z3 || kfrag signature || metadata.
+func (m *CorrectnessProof) ToBytes() ([]byte, error) {
+    data, err := pointsToBytes(m.PointE2, m.PointV2, m.PointKFragCommitment, m.PointKFragPok)
+    if err != nil {
+        return nil, err
+    }
+
+    sig, err := m.BNSig.Bytes()
+    if err != nil {
+        return nil, err
+    }
+
+    kfragSignature, err := m.KFragSignature.ToBytes()
+    if err != nil {
+        return nil, err
+    }
+
+    data = append(data, sig...)
+    data = append(data, kfragSignature...)
+    return append(data, m.Metadata...), nil
+}
+
+func (m *CorrectnessProof) Copy() (*CorrectnessProof, error) {
+    points := []*math.Point{m.PointE2, m.PointV2, m.PointKFragCommitment, m.PointKFragPok}
+    copies := make([]*math.Point, len(points))
+    var err error
+    for i, point := range points {
+        copies[i], err = point.Copy()
+        if err != nil {
+            for _, point := range copies[:i] {
+                point.Free()
+            }
+            return nil, err
+        }
+    }
+
+    bnSig, err := m.BNSig.Copy()
+    if err != nil {
+        for _, point := range copies {
+            point.Free()
+        }
+        return nil, err
+    }
+
+    proof := &CorrectnessProof{PointE2: copies[0], PointV2: copies[1],
+        PointKFragCommitment: copies[2], PointKFragPok: copies[3], BNSig: bnSig}
+
+    proof.KFragSignature, err = m.KFragSignature.Copy()
+    if err != nil {
+        proof.Free()
+        return nil, err
+    }
+
+    if m.Metadata != nil {
+        proof.Metadata = append([]byte{}, m.Metadata...)
+    }
+    return proof, nil
+}
+
+func (m *CorrectnessProof) Free() {
+    for _, point := range []*math.Point{m.PointE2, m.PointV2,
+            m.PointKFragCommitment, m.PointKFragPok} {
+        if point != nil {
+            point.Free()
+        }
+    }
+    m.BNSig.Free()
+    if m.KFragSignature != nil {
+        m.KFragSignature.Free()
+    }
+}
+
+// Returns the challenge h of the proof, the hash of
+// (e, e1, e2, v, v1, v2, u, u1, u2, metadata).
+func proofChallenge(capsule *Capsule, cfrag *CFrag, proof *CorrectnessProof) (*math.ModBigNum, error) {
+    params := capsule.Params
+
+    return math.HashItemsToModBN(params, nil, capsule.PointE, cfrag.PointE1, proof.PointE2,
+        capsule.PointV, cfrag.PointV1, proof.PointV2,
+        params.U, proof.PointKFragCommitment, proof.PointKFragPok, proof.Metadata)
+}
+
+// Attaches to the CFrag a CorrectnessProof that it is the re-encryption
+// of the Capsule with the KFrag, binding the optional metadata to it.
+func proveCorrectness(cfrag *CFrag, kfrag *KFrag, capsule *Capsule, metadata []byte) error {
+    params := capsule.Params
+    curve := params.Curve
+
+    t, err := math.GenRandModBN(curve)
+    if err != nil {
+        return err
+    }
+    defer t.Free()
+
+    proof := &CorrectnessProof{Metadata: append([]byte{}, metadata...)}
+
+    points := make([]*math.Point, 3)
+    for i, base := range []*math.Point{capsule.PointE, capsule.PointV, params.U} {
+        points[i], err = newPoint(curve)
+        if err != nil {
+            for _, point := range points[:i] {
+                point.Free()
+            }
+            return err
+        }
+
+        err = points[i].Mul(base, t)
+        if err != nil {
+            for _, point := range points[:i + 1] {
+                point.Free()
+            }
+            return err
+        }
+    }
+    proof.PointE2 = points[0]
+    proof.PointV2 = points[1]
+    proof.PointKFragPok = points[2]
+
+    proof.PointKFragCommitment, err = kfrag.PointCommitment.Copy()
+    if err != nil {
+        proof.Free()
+        return err
+    }
+
+    h, err := proofChallenge(capsule, cfrag, proof)
+    if err != nil {
+        proof.Free()
+        return err
+    }
+    defer h.Free()
+
+    // z3 = t + h * rk
+    proof.BNSig = newModBN(curve)
+    err = proof.BNSig.Mul(h, kfrag.BNKey)
+    if err != nil {
+        proof.Free()
+        return err
+    }
+
+    err = proof.BNSig.Add(t, proof.BNSig)
+    if err != nil {
+        proof.Free()
+        return err
+    }
+
+    proof.KFragSignature, err = kfrag.Signature.Copy()
+    if err != nil {
+        proof.Free()
+        return err
+    }
+
+    cfrag.Proof = proof
+    return nil
+}
+
+// Returns whether z * a == b + h * c.
+func checkProofEquation(z *math.ModBigNum, a, b *math.Point, h *math.ModBigNum, c *math.Point) (bool, error) {
+    negH := newModBN(h.Curve)
+    defer negH.Free()
+
+    err := negH.Neg(h)
+    if err != nil {
+        return false, err
+    }
+
+    // z * a - h * c == b
+    left, err := math.MultiScalarMul([]*math.Point{a, c}, []*math.ModBigNum{z, negH})
+    if err != nil {
+        return false, err
+    }
+    defer left.Free()
+
+    return left.Equals(b)
+}
@@ -0,0 +1,279 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package umbral
+
+import (
+    "errors"
+    "fmt"
+    "runtime"
+    "github.com/nucypher/goUmbral/math"
+    "github.com/nucypher/goUmbral/openssl"
+)
+
+// Represents an ECDSA signature (r, s) over the message digested with
+// the hash function of the parameters, as produced by pyUmbral's Signer
+// with the default BLAKE2b-512.
+
+type Signature struct {
+    R *math.ModBigNum
+    S *math.ModBigNum
+}
+
+// Returns the size (in bytes) of a serialized Signature given a curve.
+func SignatureLength(curve *openssl.Curve) uint {
+    return 2 * math.ExpectedBytesLength(curve)
+}
+
+// Returns the Signature from its serialization r || s.
+func BytesToSignature(data []byte, curve *openssl.Curve) (*Signature, error) {
+    if uint(len(data)) != SignatureLength(curve) {
+        return nil, errors.New("The signature does not have the right size")
+    }
+    size := len(data) / 2
+
+    r, err := math.BytesToModBN(data[:size], curve)
+    if err != nil {
+        return nil, err
+    }
+
+    s, err := math.BytesToModBN(data[size:], curve)
+    if err != nil {
+        r.Free()
+        return nil, err
+    }
+    return &Signature{R: r, S: s}, nil
+}
+
+// Returns the Signature serialized as r || s.
+func (m *Signature) ToBytes() ([]byte, error) {
+    r, err := m.R.Bytes()
+    if err != nil {
+        return nil, err
+    }
+
+    s, err := m.S.Bytes()
+    if err != nil {
+        return nil, err
+    }
+    return append(r, s...), nil
+}
+
+// Returns the Signature from its DER encoding.
+func DERToSignature(data []byte, curve *openssl.Curve) (*Signature, error) {
+    sig, err := openssl.DERToECDSASig(data)
+    if err != nil {
+        return nil, err
+    }
+    defer openssl.FreeECDSASig(sig)
+
+    return ecdsaSigToSignature(sig, curve)
+}
+
+// Returns the Signature in DER encoding.
+func (m *Signature) ToDER() ([]byte, error) {
+    sig, err := m.toECDSASig()
+    if err != nil {
+        return nil, err
+    }
+    defer openssl.FreeECDSASig(sig)
+
+    return openssl.ECDSASigToDER(sig)
+}
+
+// Signature.Verify() checks the Signature of the message against the public key.
+//
+// Signatures with a high s are accepted, since pyUmbral does not normalize them.
+func (m *Signature) Verify(message []byte, pubKey *PublicKey) (bool, error) {
+    defer runtime.KeepAlive(pubKey)
+    curve := pubKey.Params.Curve
+
+    if !m.R.Curve.Equals(curve) || !m.S.Curve.Equals(curve) {
+        return false, errors.New("The signature does not share the curve of the public key.")
+    }
+
+    key, err := openssl.NewECKey(curve, nil, pubKey.PointKey.ECPoint)
+    if err != nil {
+        return false, err
+    }
+    defer openssl.FreeECKey(key)
+
+    sig, err := m.toECDSASig()
+    if err != nil {
+        return false, err
+    }
+    defer openssl.FreeECDSASig(sig)
+
+    digest := pubKey.Params.Hash.Sum(message)
+    return openssl.ECDSAVerify(digest, sig, key)
+}
+
+// Returns a new ECDSA_SIG holding copies of r and s.
+func (m *Signature) toECDSASig() (openssl.ECDSASig, error) {
+    defer runtime.KeepAlive(m)
+    r, err := openssl.DupBN(m.R.Bignum)
+    if err != nil {
+        return nil, err
+    }
+
+    s, err := openssl.DupBN(m.S.Bignum)
+    if err != nil {
+        openssl.FreeBigNum(r)
+        return nil, err
+    }
+
+    sig, err := openssl.NewECDSASig(r, s)
+    if err != nil {
+        openssl.FreeBigNum(r)
+        openssl.FreeBigNum(s)
+        return nil, err
+    }
+    return sig, nil
+}
+
+func (m *Signature) Copy() (*Signature, error) {
+    r, err := m.R.Copy()
+    if err != nil {
+        return nil, err
+    }
+
+    s, err := m.S.Copy()
+    if err != nil {
+        r.Free()
+        return nil, err
+    }
+    return &Signature{R: r, S: s}, nil
+}
+
+func (m *Signature) Free() {
+    m.R.Free()
+    m.S.Free()
+}
+
+// Signs messages with a private key, producing Signatures that
+// are compatible with pyUmbral.
+
+type Signer struct {
+    privKey *PrivateKey
+}
+
+// Returns a Signer for the given private key.
+//
+// The Signer does not take ownership of privKey.
+func NewSigner(privKey *PrivateKey) *Signer {
+    return &Signer{privKey}
+}
+
+func (m *Signer) GetPublicKey() *PublicKey {
+    return m.privKey.GetPublicKey()
+}
+
+// Returns the Signature of the message.
+//
+// On secp256k1 the Signature is normalized to a low s, as required
+// by the other implementations of Umbral on that curve.
+// The Signature must be freed by the calling function.
+func (m *Signer) Sign(message []byte) (*Signature, error) {
+    defer runtime.KeepAlive(m)
+    curve := m.privKey.Params.Curve
+
+    key, err := openssl.NewECKey(curve, m.privKey.BNKey.Bignum,
+        m.privKey.PubKey.PointKey.ECPoint)
+    if err != nil {
+        return nil, err
+    }
+    defer openssl.FreeECKey(key)
+
+    digest := m.privKey.Params.Hash.Sum(message)
+    sig, err := openssl.ECDSASign(digest, key)
+    if err != nil {
+        return nil, err
+    }
+    defer openssl.FreeECDSASig(sig)
+
+    signature, err := ecdsaSigToSignature(sig, curve)
+    if err != nil {
+        return nil, err
+    }
+
+    if curve.NID == openssl.SECP256K1 {
+        err = signature.normalizeS()
+        if err != nil {
+            signature.Free()
+            return nil, err
+        }
+    }
+    return signature, nil
+}
+
+// Replaces s with n - s if s is greater than n / 2.
+func (m *Signature) normalizeS() error {
+    negS := newModBN(m.S.Curve)
+
+    err := negS.Neg(m.S)
+    if err != nil {
+        negS.Free()
+        return err
+    }
+
+    if negS.Compare(m.S) < 0 {
+        m.S.Free()
+        m.S = negS
+    } else {
+        negS.Free()
+    }
+    return nil
+}
+
+// Returns a Signature holding copies of the r and s of the ECDSA_SIG.
+func ecdsaSigToSignature(sig openssl.ECDSASig, curve *openssl.Curve) (*Signature, error) {
+    sigR, sigS := openssl.GetECDSASigRS(sig)
+
+    r, err := openssl.DupBN(sigR)
+    if err != nil {
+        return nil, err
+    }
+
+    s, err := openssl.DupBN(sigS)
+    if err != nil {
+        openssl.FreeBigNum(r)
+        return nil, err
+    }
+
+    rBN, err := math.NewModBigNum(r, curve)
+    if err != nil {
+        openssl.FreeBigNum(r)
+        openssl.FreeBigNum(s)
+        return nil, signatureRangeError(err)
+    }
+
+    sBN, err := math.NewModBigNum(s, curve)
+    if err != nil {
+        rBN.Free()
+        openssl.FreeBigNum(s)
+        return nil, signatureRangeError(err)
+    }
+    return &Signature{R: rBN, S: sBN}, nil
+}
+
+// Returns the error of NewModBigNum for r or s, explaining it if r or s
+// is not within the order of the curve.
+func signatureRangeError(err error) error {
+    if errors.Is(err, math.ErrScalarOutOfRange) {
+        return fmt.Errorf("The signature is not within the order of the curve: %w", err)
+    }
+    return err
+}
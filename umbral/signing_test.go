@@ -0,0 +1,184 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package umbral_test
+
+import (
+    "errors"
+    "testing"
+    "github.com/nucypher/goUmbral/math"
+    "github.com/nucypher/goUmbral/openssl"
+    "github.com/nucypher/goUmbral/umbral"
+)
+
+func TestSignVerify(t *testing.T) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Error(err)
+    }
+    defer curve.Free()
+
+    params, err := math.NewUmbralParameters(curve)
+    if err != nil {
+        t.Error(err)
+    }
+
+    privKey, err := umbral.GenPrivateKey(params)
+    if err != nil {
+        t.Error(err)
+    }
+    defer privKey.Free()
+
+    otherKey, err := umbral.GenPrivateKey(params)
+    if err != nil {
+        t.Error(err)
+    }
+    defer otherKey.Free()
+
+    signer := umbral.NewSigner(privKey)
+    message := []byte("attack at dawn")
+
+    signature, err := signer.Sign(message)
+    if err != nil {
+        t.Error(err)
+    }
+    defer signature.Free()
+
+    data, err := signature.ToBytes()
+    if err != nil {
+        t.Error(err)
+    }
+
+    newSignature, err := umbral.BytesToSignature(data, curve)
+    if err != nil {
+        t.Error(err)
+    }
+    defer newSignature.Free()
+
+    valid, err := newSignature.Verify(message, signer.GetPublicKey())
+    if err != nil {
+        t.Error(err)
+    }
+    if !valid {
+        t.Error("The signature did not verify")
+    }
+
+    valid, err = newSignature.Verify([]byte("attack at dusk"), signer.GetPublicKey())
+    if err != nil {
+        t.Error(err)
+    }
+    if valid {
+        t.Error("The signature verified a different message")
+    }
+
+    valid, err = newSignature.Verify(message, otherKey.GetPublicKey())
+    if err != nil {
+        t.Error(err)
+    }
+    if valid {
+        t.Error("The signature verified under a different key")
+    }
+}
+
+func TestSignatureDER(t *testing.T) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Error(err)
+    }
+    defer curve.Free()
+
+    params, err := math.NewUmbralParameters(curve)
+    if err != nil {
+        t.Error(err)
+    }
+
+    privKey, err := umbral.GenPrivateKey(params)
+    if err != nil {
+        t.Error(err)
+    }
+    defer privKey.Free()
+
+    signer := umbral.NewSigner(privKey)
+    message := []byte("attack at dawn")
+
+    for i := 0; i < 16; i++ {
+        signature, err := signer.Sign(message)
+        if err != nil {
+            t.Error(err)
+        }
+
+        // On secp256k1 the signatures must have a low s.
+        negS, err := math.IntToModBN(1, curve)
+        if err != nil {
+            t.Error(err)
+        }
+        err = negS.Neg(signature.S)
+        if err != nil {
+            t.Error(err)
+        }
+        if signature.S.Compare(negS) > 0 {
+            t.Error("The signature does not have a low s")
+        }
+        negS.Free()
+
+        der, err := signature.ToDER()
+        if err != nil {
+            t.Error(err)
+        }
+
+        newSignature, err := umbral.DERToSignature(der, curve)
+        if err != nil {
+            t.Error(err)
+        }
+
+        if !newSignature.R.Equals(signature.R) || !newSignature.S.Equals(signature.S) {
+            t.Error("The DER encoding did not round trip")
+        }
+
+        valid, err := newSignature.Verify(message, signer.GetPublicKey())
+        if err != nil {
+            t.Error(err)
+        }
+        if !valid {
+            t.Error("The signature did not verify")
+        }
+        signature.Free()
+        newSignature.Free()
+    }
+}
+
+func TestSignatureOutOfRange(t *testing.T) {
+    curve, err := openssl.NewCurve(openssl.SECP256K1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer curve.Free()
+
+    order, err := openssl.BNToBytes(curve.Order)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // SEQUENCE { INTEGER n, INTEGER 1 }, where the order n needs a
+    // leading zero to be positive.
+    der := append([]byte{0x30, 0x26, 0x02, 0x21, 0x00}, order...)
+    der = append(der, 0x02, 0x01, 0x01)
+
+    _, err = umbral.DERToSignature(der, curve)
+    if !errors.Is(err, math.ErrScalarOutOfRange) {
+        t.Error("Got:", err, "Expected:", math.ErrScalarOutOfRange)
+    }
+}
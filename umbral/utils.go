@@ -0,0 +1,69 @@
+// Copyright (C) 2018 NuCypher
+//
+// This file is part of goUmbral.
+//
+// goUmbral is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// goUmbral is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with goUmbral. If not, see <https://www.gnu.org/licenses/>.
+package umbral
+
+import (
+    "io"
+    "golang.org/x/crypto/hkdf"
+    "github.com/nucypher/goUmbral/math"
+    "github.com/nucypher/goUmbral/openssl"
+)
+
+// Returns the concatenation of the compressed serializations of the Points,
+// which are not modified.
+func pointsToBytes(points ...*math.Point) ([]byte, error) {
+    encoded, err := math.BatchToBytes(points, true)
+    if err != nil {
+        return nil, err
+    }
+
+    var data []byte
+    for _, pointBytes := range encoded {
+        data = append(data, pointBytes...)
+    }
+    return data, nil
+}
+
+// Returns a new Point to hold the result of an operation.
+func newPoint(curve *openssl.Curve) (*math.Point, error) {
+    point, err := openssl.NewECPoint(curve)
+    if err != nil {
+        return nil, err
+    }
+    return math.NewPoint(point, curve)
+}
+
+// Returns a new ModBigNum to hold the result of an operation.
+func newModBN(curve *openssl.Curve) *math.ModBigNum {
+    return math.NewZeroModBN(curve)
+}
+
+// Derives a key of the given length from the compressed Point
+// with HKDF using the given hash function.
+func kdf(point *math.Point, hashFunc math.HashFunction, keyLength int) ([]byte, error) {
+    data, err := point.ToBytes(true)
+    if err != nil {
+        return nil, err
+    }
+
+    key := make([]byte, keyLength)
+    _, err = io.ReadFull(hkdf.New(hashFunc.New, data, nil, nil), key)
+    if err != nil {
+        return nil, err
+    }
+    return key, nil
+}